@@ -0,0 +1,137 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/charmbracelet/x/term"
+	"rsss/pkg/config"
+)
+
+// stdin is shared by prompts so that buffered input is not lost between them
+var stdin = bufio.NewReader(os.Stdin)
+
+// runCredential manages the credential store used by authenticated feeds
+func runCredential(args []string) error {
+	if len(args) == 0 {
+		return errors.New("missing credential command")
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+	store, err := config.LoadCredentialStore(cfg.CredentialsFile)
+	if err != nil {
+		return err
+	}
+
+	command, args := args[0], args[1:]
+	switch command {
+	case "list":
+		for _, name := range store.Names() {
+			cred, _ := store.Get(name)
+			source := "encrypted"
+			switch {
+			case cred.Env != "":
+				source = "env " + cred.Env
+			case cred.Command != "":
+				source = "command " + cred.Command
+			}
+			fmt.Printf("%s\t%s\t%s\n", name, cred.Username, source)
+		}
+		return nil
+	case "set":
+		if len(args) < 1 || len(args) > 2 {
+			return errors.New("usage: rsss --credential set NAME [USERNAME]")
+		}
+		if err := unlockStore(store); err != nil {
+			return err
+		}
+		secret, err := readSecret("Password or token: ")
+		if err != nil {
+			return err
+		}
+		if err := store.SetSecret(args[0], optionalArg(args, 1), secret); err != nil {
+			return err
+		}
+	case "env":
+		if len(args) < 2 || len(args) > 3 {
+			return errors.New("usage: rsss --credential env NAME VARIABLE [USERNAME]")
+		}
+		store.SetEnv(args[0], optionalArg(args, 2), args[1])
+	case "command":
+		if len(args) < 2 || len(args) > 3 {
+			return errors.New("usage: rsss --credential command NAME 'COMMAND' [USERNAME]")
+		}
+		store.SetCommand(args[0], optionalArg(args, 2), args[1])
+	case "remove":
+		if len(args) != 1 {
+			return errors.New("usage: rsss --credential remove NAME")
+		}
+		if _, ok := store.Get(args[0]); !ok {
+			return fmt.Errorf("unknown credential %q", args[0])
+		}
+		store.Remove(args[0])
+	default:
+		return fmt.Errorf("unknown credential command %q", command)
+	}
+
+	if err := store.Save(cfg.CredentialsFile); err != nil {
+		return fmt.Errorf("failed to save credentials: %w", err)
+	}
+	fmt.Printf("Saved credentials to %s\n", cfg.CredentialsFile)
+	return nil
+}
+
+// unlockStore asks for the store's passphrase, or for a new one if the store
+// has none yet
+func unlockStore(store *config.CredentialStore) error {
+	if !store.HasPassphrase() {
+		passphrase, err := readSecret("New passphrase for stored credentials: ")
+		if err != nil {
+			return err
+		}
+		confirm, err := readSecret("Repeat passphrase: ")
+		if err != nil {
+			return err
+		}
+		if passphrase != confirm {
+			return errors.New("passphrases do not match")
+		}
+		return store.Unlock(passphrase)
+	}
+
+	passphrase, err := readSecret("Passphrase: ")
+	if err != nil {
+		return err
+	}
+	return store.Unlock(passphrase)
+}
+
+// readSecret prompts for a value without echoing it when stdin is a terminal
+func readSecret(prompt string) (string, error) {
+	fmt.Print(prompt)
+	if term.IsTerminal(os.Stdin.Fd()) {
+		secret, err := term.ReadPassword(os.Stdin.Fd())
+		fmt.Println()
+		return string(secret), err
+	}
+
+	line, err := stdin.ReadString('\n')
+	if err != nil && line == "" {
+		return "", fmt.Errorf("failed to read input: %w", err)
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+// optionalArg returns args[i], or "" if there are not enough arguments
+func optionalArg(args []string, i int) string {
+	if i < len(args) {
+		return args[i]
+	}
+	return ""
+}
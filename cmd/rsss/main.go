@@ -1,8 +1,12 @@
 package main
 
 import (
+	"bufio"
+	"errors"
 	"fmt"
 	"os"
+	"strconv"
+	"strings"
 	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
@@ -27,6 +31,11 @@ func main() {
 			fmt.Printf("Error running TUI: %v\n", err)
 			os.Exit(1)
 		}
+	case "--credential":
+		if err := runCredential(os.Args[2:]); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
 	default:
 		url := os.Args[1]
 		if err := runCLI(url); err != nil {
@@ -38,11 +47,19 @@ func main() {
 
 func printUsage() {
 	fmt.Println("Usage: rsss <RSS_URL>")
+	fmt.Println("       rsss file:///path/to/feed.xml | 'exec:COMMAND' | -")
 	fmt.Println("       rsss --tui [RSS_URL]")
 	fmt.Println("       rsss --menu")
+	fmt.Println("       rsss --credential set NAME [USERNAME]")
+	fmt.Println("       rsss --credential env NAME VARIABLE [USERNAME]")
+	fmt.Println("       rsss --credential command NAME 'COMMAND' [USERNAME]")
+	fmt.Println("       rsss --credential remove NAME")
+	fmt.Println("       rsss --credential list")
 	fmt.Println("Example: rsss https://feeds.feedburner.com/oreilly/radar")
 	fmt.Println("         rsss --tui https://feeds.bbci.co.uk/news/rss.xml")
+	fmt.Println("         curl -s https://example.com/feed.xml | rsss -")
 	fmt.Println("         rsss --menu")
+	fmt.Println("         rsss --credential command jira 'pass show jira' me@example.com")
 }
 
 func runTUI(url string) error {
@@ -57,10 +74,16 @@ func runTUI(url string) error {
 	}
 
 	if url != "" {
-		feeds.Feeds = []rss.FeedInfo{{Name: "Command Line Feed", URL: url}}
+		// Changes to the command line feed must not overwrite the feeds file
+		feeds = &config.FeedConfig{Feeds: []rss.FeedInfo{{Name: "Command Line Feed", URL: url}}, Ephemeral: true}
 	}
 
 	rssClient := rss.NewClient(10 * time.Second)
+	rssClient.SetConcurrency(cfg.MaxConcurrentFetches, cfg.MaxFetchesPerHost)
+	rssClient.SetMaxBodySize(cfg.MaxFeedSize)
+	if err := rssClient.SetTransport(cfg.Transport); err != nil {
+		return fmt.Errorf("invalid transport config: %w", err)
+	}
 	model := tui.NewModel(cfg, feeds, rssClient)
 
 	p := tea.NewProgram(model)
@@ -75,10 +98,36 @@ func runTUI(url string) error {
 }
 
 func runCLI(url string) error {
-	fmt.Printf("Fetching RSS feed from: %s\n\n", url)
+	if url == "-" {
+		feed, err := rss.ParseFeedReader("", os.Stdin)
+		if err != nil {
+			return err
+		}
+		feed.ResolveURLs("")
+		displayFeed(feed)
+		return nil
+	}
 
+	fmt.Printf("Fetching feed from: %s\n\n", url)
+
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
 	client := rss.NewClient(10 * time.Second)
+	client.SetMaxBodySize(cfg.MaxFeedSize)
+	if err := client.SetTransport(cfg.Transport); err != nil {
+		return fmt.Errorf("invalid transport config: %w", err)
+	}
 	feed, err := client.FetchFeed(url)
+	if errors.Is(err, rss.ErrHTMLPage) {
+		url, err = chooseDiscoveredFeed(client, url)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Fetching feed from: %s\n\n", url)
+		feed, err = client.FetchFeed(url)
+	}
 	if err != nil {
 		return err
 	}
@@ -87,18 +136,61 @@ func runCLI(url string) error {
 	return nil
 }
 
-func displayFeed(feed *rss.RSS) {
-	fmt.Printf("Feed: %s\n", feed.Channel.Title)
-	fmt.Printf("Description: %s\n", feed.Channel.Description)
-	fmt.Printf("Link: %s\n\n", feed.Channel.Link)
+// chooseDiscoveredFeed looks for feeds advertised by an HTML page and asks
+// the user to pick one when there are several
+func chooseDiscoveredFeed(client *rss.Client, pageURL string) (string, error) {
+	fmt.Println("The URL is a web page, looking for feeds...")
 
-	for i, item := range feed.Channel.Items {
+	feeds, err := client.Discover(pageURL)
+	if err != nil {
+		return "", err
+	}
+
+	switch len(feeds) {
+	case 0:
+		return "", fmt.Errorf("no feeds found at %s", pageURL)
+	case 1:
+		return feeds[0].URL, nil
+	}
+
+	fmt.Println("Found multiple feeds:")
+	for i, feed := range feeds {
+		title := feed.Title
+		if title == "" {
+			title = feed.Type
+		}
+		fmt.Printf("  %d. %s (%s)\n", i+1, title, feed.URL)
+	}
+
+	reader := bufio.NewReader(os.Stdin)
+	for {
+		fmt.Printf("Choose a feed [1-%d]: ", len(feeds))
+		line, err := reader.ReadString('\n')
+		if choice, convErr := strconv.Atoi(strings.TrimSpace(line)); convErr == nil && choice >= 1 && choice <= len(feeds) {
+			return feeds[choice-1].URL, nil
+		}
+		if err != nil {
+			return "", fmt.Errorf("no feed selected")
+		}
+	}
+}
+
+func displayFeed(feed *rss.Feed) {
+	fmt.Printf("Feed: %s\n", feed.Title)
+	fmt.Printf("Description: %s\n", feed.Description)
+	fmt.Printf("Link: %s\n\n", feed.Link)
+
+	for i, entry := range feed.Entries {
 		if i >= 10 {
 			break
 		}
-		fmt.Printf("Title: %s\n", item.Title)
-		fmt.Printf("Link: %s\n", item.Link)
-		fmt.Printf("Date: %s\n", item.PubDate)
-		fmt.Printf("Description: %s\n\n", item.Description)
+		fmt.Printf("Title: %s\n", entry.Title)
+		fmt.Printf("Link: %s\n", entry.Link)
+		if entry.PubDate.IsZero() {
+			fmt.Printf("Date: unknown\n")
+		} else {
+			fmt.Printf("Date: %s\n", entry.PubDate.Format(time.RFC1123Z))
+		}
+		fmt.Printf("Description: %s\n\n", entry.Description)
 	}
 }
@@ -0,0 +1,120 @@
+package browser
+
+import (
+	"fmt"
+	"mime"
+	"net/url"
+	"os/exec"
+	"path"
+	"strings"
+)
+
+// Placeholder is replaced with the media URL or file path in player command templates
+const Placeholder = "{}"
+
+// PlayerCommand returns the command template configured for a MIME type.
+// Templates are looked up by exact type, then by "type/*", then by "*".
+// When mimeType is empty it is guessed from the target's file extension.
+func PlayerCommand(players map[string]string, mimeType, target string) (string, error) {
+	if mimeType == "" {
+		mimeType = guessMimeType(target)
+	}
+	mediaType, _, err := mime.ParseMediaType(mimeType)
+	if err != nil {
+		mediaType = strings.ToLower(mimeType)
+	}
+
+	candidates := []string{mediaType}
+	if major, _, ok := strings.Cut(mediaType, "/"); ok {
+		candidates = append(candidates, major+"/*")
+	}
+	candidates = append(candidates, "*")
+
+	for _, candidate := range candidates {
+		if template, ok := players[candidate]; ok && strings.TrimSpace(template) != "" {
+			return template, nil
+		}
+	}
+	return "", fmt.Errorf("no media player configured for %q", mediaType)
+}
+
+// Play launches the media player configured for the MIME type with the given
+// URL or file path. The target is passed as a single argument and never
+// interpreted by a shell.
+func Play(players map[string]string, mimeType, target string) error {
+	template, err := PlayerCommand(players, mimeType, target)
+	if err != nil {
+		return err
+	}
+
+	args := expandTemplate(template, target)
+	cmd := exec.Command(args[0], args[1:]...)
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start %s: %w", args[0], err)
+	}
+
+	// Reap the process once the player exits
+	go cmd.Wait()
+	return nil
+}
+
+// expandTemplate splits a command template into arguments and substitutes the
+// target for the placeholder, appending it if the template has none
+func expandTemplate(template, target string) []string {
+	fields := splitArgs(template)
+	substituted := false
+	for i, field := range fields {
+		if strings.Contains(field, Placeholder) {
+			fields[i] = strings.ReplaceAll(field, Placeholder, target)
+			substituted = true
+		}
+	}
+	if !substituted {
+		fields = append(fields, target)
+	}
+	return fields
+}
+
+// splitArgs splits a command line on whitespace, honouring single and double quotes
+func splitArgs(s string) []string {
+	var args []string
+	var current strings.Builder
+	var quote rune
+	inArg := false
+
+	for _, r := range s {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				current.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote = r
+			inArg = true
+		case r == ' ' || r == '\t':
+			if inArg {
+				args = append(args, current.String())
+				current.Reset()
+				inArg = false
+			}
+		default:
+			current.WriteRune(r)
+			inArg = true
+		}
+	}
+	if inArg {
+		args = append(args, current.String())
+	}
+	return args
+}
+
+// guessMimeType guesses a MIME type from the extension of a URL or file path
+func guessMimeType(target string) string {
+	p := target
+	if u, err := url.Parse(target); err == nil && u.Scheme != "" {
+		p = u.Path
+	}
+	return mime.TypeByExtension(path.Ext(p))
+}
@@ -0,0 +1,60 @@
+package browser
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPlayerCommand(t *testing.T) {
+	players := map[string]string{
+		"audio/mpeg": "mpg123 {}",
+		"audio/*":    "mpv --no-video {}",
+		"*":          "vlc",
+		"video/mp4":  "",
+	}
+
+	testCases := []struct {
+		mimeType string
+		target   string
+		expected string
+	}{
+		{"audio/mpeg", "https://example.com/a.mp3", "mpg123 {}"},
+		{"audio/ogg; codecs=opus", "https://example.com/a.ogg", "mpv --no-video {}"},
+		{"video/webm", "https://example.com/a.webm", "vlc"},
+		{"video/mp4", "https://example.com/a.mp4", "vlc"}, // blank templates are skipped
+	}
+
+	for _, tc := range testCases {
+		template, err := PlayerCommand(players, tc.mimeType, tc.target)
+		if err != nil {
+			t.Errorf("PlayerCommand(%q) returned error: %v", tc.mimeType, err)
+			continue
+		}
+		if template != tc.expected {
+			t.Errorf("PlayerCommand(%q) = %q, expected %q", tc.mimeType, template, tc.expected)
+		}
+	}
+
+	if _, err := PlayerCommand(map[string]string{"video/*": "mpv {}"}, "audio/mpeg", "a.mp3"); err == nil {
+		t.Error("Expected error when no player matches")
+	}
+}
+
+func TestExpandTemplate(t *testing.T) {
+	testCases := []struct {
+		template string
+		expected []string
+	}{
+		{"mpv {}", []string{"mpv", "https://example.com/a b.mp3"}},
+		{"vlc", []string{"vlc", "https://example.com/a b.mp3"}},
+		{`mpv --title="My Podcast" --input={}`, []string{"mpv", "--title=My Podcast", "--input=https://example.com/a b.mp3"}},
+		{"'/opt/my player/play' {}", []string{"/opt/my player/play", "https://example.com/a b.mp3"}},
+	}
+
+	for _, tc := range testCases {
+		result := expandTemplate(tc.template, "https://example.com/a b.mp3")
+		if !reflect.DeepEqual(result, tc.expected) {
+			t.Errorf("expandTemplate(%q) = %q, expected %q", tc.template, result, tc.expected)
+		}
+	}
+}
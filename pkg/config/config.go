@@ -11,36 +11,74 @@ import (
 
 // Config represents the application configuration
 type Config struct {
-	RefreshRate         time.Duration `json:"refresh_rate"`
-	FeedsFile           string        `json:"feeds_file"`
-	ColorTheme          string        `json:"color_theme"`
-	SeenArticlesFile    string        `json:"seen_articles_file"`
-	EnableNotifications bool          `json:"enable_notifications"`
-	ConfigFile          string        `json:"-"`
+	RefreshRate          time.Duration        `json:"refresh_rate"`
+	FeedsFile            string               `json:"feeds_file"`
+	ColorTheme           string               `json:"color_theme"`
+	SeenArticlesFile     string               `json:"seen_articles_file"`
+	CacheFile            string               `json:"cache_file"`
+	CredentialsFile      string               `json:"credentials_file"`
+	EnableNotifications  bool                 `json:"enable_notifications"`
+	MaxConcurrentFetches int                  `json:"max_concurrent_fetches"`
+	MaxFetchesPerHost    int                  `json:"max_fetches_per_host"`
+	MaxFeedSize          int64                `json:"max_feed_size"` // Bytes, after decompression
+	Transport            rss.TransportOptions `json:"transport"`     // Proxy and TLS settings, overridable per feed
+	DownloadDir          string               `json:"download_dir"`
+	MediaPlayers         map[string]string    `json:"media_players"` // MIME type or pattern to player command template
+	ConfigFile           string               `json:"-"`
 }
 
-// FeedConfig represents the feeds configuration
+// FeedConfig represents the feeds configuration. Ephemeral feeds, such as
+// one given on the command line, are never saved.
 type FeedConfig struct {
-	Feeds []rss.FeedInfo `json:"feeds"`
+	Feeds     []rss.FeedInfo `json:"feeds"`
+	Ephemeral bool           `json:"-"`
 }
 
-// SeenArticles represents the seen articles tracking
+// seenArticlesVersion is the current format of the seen articles file.
+// Version 0 keyed articles by link, version 1 by article ID; version 2 keys
+// them by rss.Article.Key, which adds the feed URL.
+const seenArticlesVersion = 2
+
+// SeenArticles represents the seen articles tracking, keyed by rss.Article.Key
 type SeenArticles struct {
+	Version  int             `json:"version"`
 	Articles map[string]bool `json:"articles"`
 }
 
+// feedCacheVersion is the current format of the feed cache file. Bump it
+// whenever rss.Feed changes so that stale entries are not revalidated forever.
+const feedCacheVersion = 2
+
+// FeedCache represents the persisted feed cache, keyed by feed URL. FirstSeen
+// records when undated articles were first seen so they keep their order.
+type FeedCache struct {
+	Version   int                       `json:"version"`
+	Feeds     map[string]rss.CacheEntry `json:"feeds"`
+	FirstSeen map[string]time.Time      `json:"first_seen,omitempty"`
+}
+
 // DefaultConfig returns the default configuration
 func DefaultConfig() *Config {
 	homeDir, _ := os.UserHomeDir()
 	configDir := filepath.Join(homeDir, ".config", "rsss")
 
 	return &Config{
-		RefreshRate:         5 * time.Minute,
-		FeedsFile:           filepath.Join(configDir, "feeds.json"),
-		ColorTheme:          "default",
-		SeenArticlesFile:    filepath.Join(configDir, "seen.json"),
-		EnableNotifications: true,
-		ConfigFile:          filepath.Join(configDir, "config.json"),
+		RefreshRate:          5 * time.Minute,
+		FeedsFile:            filepath.Join(configDir, "feeds.json"),
+		ColorTheme:           "default",
+		SeenArticlesFile:     filepath.Join(configDir, "seen.json"),
+		CacheFile:            filepath.Join(configDir, "cache.json"),
+		CredentialsFile:      filepath.Join(configDir, "credentials.json"),
+		EnableNotifications:  true,
+		MaxConcurrentFetches: rss.DefaultMaxConcurrency,
+		MaxFetchesPerHost:    rss.DefaultMaxPerHost,
+		MaxFeedSize:          rss.DefaultMaxBodySize,
+		DownloadDir:          filepath.Join(homeDir, "Downloads", "rsss"),
+		MediaPlayers: map[string]string{
+			"audio/*": "mpv --no-video {}",
+			"video/*": "mpv {}",
+		},
+		ConfigFile: filepath.Join(configDir, "config.json"),
 	}
 }
 
@@ -108,6 +146,10 @@ func LoadFeeds(filename string) (*FeedConfig, error) {
 
 // Save saves the feed configuration to file
 func (f *FeedConfig) Save(filename string) error {
+	if f.Ephemeral {
+		return nil
+	}
+
 	dir := filepath.Dir(filename)
 	if err := os.MkdirAll(dir, 0755); err != nil {
 		return err
@@ -121,9 +163,14 @@ func (f *FeedConfig) Save(filename string) error {
 	return os.WriteFile(filename, data, 0644)
 }
 
+// NewSeenArticles creates seen articles tracking in the current format
+func NewSeenArticles(articles map[string]bool) *SeenArticles {
+	return &SeenArticles{Version: seenArticlesVersion, Articles: articles}
+}
+
 // LoadSeenArticles loads seen articles from file
 func LoadSeenArticles(filename string) (*SeenArticles, error) {
-	seen := &SeenArticles{Articles: make(map[string]bool)}
+	seen := NewSeenArticles(make(map[string]bool))
 
 	if _, err := os.Stat(filename); os.IsNotExist(err) {
 		return seen, nil
@@ -134,11 +181,18 @@ func LoadSeenArticles(filename string) (*SeenArticles, error) {
 		return seen, err
 	}
 
-	if err := json.Unmarshal(data, seen); err != nil {
+	var loaded SeenArticles
+	if err := json.Unmarshal(data, &loaded); err != nil {
 		return seen, err
 	}
 
-	return seen, nil
+	// Entries from older versions use a different key, so start over rather
+	// than reporting every article as new
+	if loaded.Version < seenArticlesVersion || loaded.Articles == nil {
+		return seen, nil
+	}
+
+	return &loaded, nil
 }
 
 // Save saves the seen articles to file
@@ -153,5 +207,53 @@ func (s *SeenArticles) Save(filename string) error {
 		return err
 	}
 
+	return os.WriteFile(filename, data, 0644)
+}
+
+// NewFeedCache creates a persistable snapshot of a feed cache
+func NewFeedCache(cache *rss.Cache) *FeedCache {
+	return &FeedCache{Version: feedCacheVersion, Feeds: cache.Entries(), FirstSeen: cache.FirstSeenTimes()}
+}
+
+// LoadFeedCache loads the feed cache from file
+func LoadFeedCache(filename string) (*rss.Cache, error) {
+	empty := rss.NewCache(nil)
+
+	if _, err := os.Stat(filename); os.IsNotExist(err) {
+		return empty, nil
+	}
+
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return empty, err
+	}
+
+	var loaded FeedCache
+	if err := json.Unmarshal(data, &loaded); err != nil {
+		return empty, err
+	}
+
+	// Entries from other versions may be missing fields, so fetch everything again
+	if loaded.Version != feedCacheVersion {
+		return empty, nil
+	}
+
+	cache := rss.NewCache(loaded.Feeds)
+	cache.SetFirstSeenTimes(loaded.FirstSeen)
+	return cache, nil
+}
+
+// Save saves the feed cache to file
+func (f *FeedCache) Save(filename string) error {
+	dir := filepath.Dir(filename)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(f)
+	if err != nil {
+		return err
+	}
+
 	return os.WriteFile(filename, data, 0644)
 }
\ No newline at end of file
@@ -1,8 +1,10 @@
 package config
 
 import (
+	"errors"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
@@ -127,4 +129,147 @@ func TestFeedConfigSave(t *testing.T) {
 	if loadedFeeds.Feeds[0].Name != "Test Feed" {
 		t.Errorf("Expected feed name 'Test Feed', got '%s'", loadedFeeds.Feeds[0].Name)
 	}
-}
\ No newline at end of file
+}
+
+func TestLoadSeenArticlesLegacy(t *testing.T) {
+	tempDir := t.TempDir()
+	seenFile := filepath.Join(tempDir, "seen.json")
+
+	// Files without a version were keyed by link and are discarded
+	legacy := `{"articles": {"https://example.com/1": true}}`
+	if err := os.WriteFile(seenFile, []byte(legacy), 0644); err != nil {
+		t.Fatalf("Failed to write seen file: %v", err)
+	}
+
+	seen, err := LoadSeenArticles(seenFile)
+	if err != nil {
+		t.Fatalf("Failed to load seen articles: %v", err)
+	}
+	if len(seen.Articles) != 0 {
+		t.Errorf("Expected legacy articles to be discarded, got %v", seen.Articles)
+	}
+
+	// Version 1 keyed articles by ID alone, which collides across feeds
+	if err := os.WriteFile(seenFile, []byte(`{"version": 1, "articles": {"1": true}}`), 0644); err != nil {
+		t.Fatalf("Failed to write seen file: %v", err)
+	}
+	if seen, err = LoadSeenArticles(seenFile); err != nil || len(seen.Articles) != 0 {
+		t.Errorf("Expected version 1 articles to be discarded, got %v (%v)", seen.Articles, err)
+	}
+
+	seen.Articles["tag:example.com,2024:1"] = true
+	if err := seen.Save(seenFile); err != nil {
+		t.Fatalf("Failed to save seen articles: %v", err)
+	}
+
+	seen, err = LoadSeenArticles(seenFile)
+	if err != nil {
+		t.Fatalf("Failed to load seen articles: %v", err)
+	}
+	if !seen.Articles["tag:example.com,2024:1"] {
+		t.Errorf("Expected saved article ID to be kept, got %v", seen.Articles)
+	}
+}
+
+func TestFeedCacheSaveLoad(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "cache.json")
+
+	cache := rss.NewCache(nil)
+	cache.Set("https://example.com/feed", rss.CacheEntry{
+		ETag: `"abc"`,
+		Feed: &rss.Feed{Title: "Example", Entries: []rss.Entry{{ID: "1", Title: "Hello"}}},
+	})
+	firstSeen := cache.FirstSeen("undated", time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC))
+	if err := NewFeedCache(cache).Save(filename); err != nil {
+		t.Fatalf("Failed to save feed cache: %v", err)
+	}
+
+	loaded, err := LoadFeedCache(filename)
+	if err != nil {
+		t.Fatalf("Failed to load feed cache: %v", err)
+	}
+	entry, ok := loaded.Get("https://example.com/feed")
+	if !ok || entry.ETag != `"abc"` || entry.Feed.Title != "Example" || len(entry.Feed.Entries) != 1 {
+		t.Errorf("Expected cached feed to round-trip, got %+v", entry)
+	}
+	if seen := loaded.FirstSeen("undated", time.Now()); !seen.Equal(firstSeen) {
+		t.Errorf("Expected first-seen time %v to round-trip, got %v", firstSeen, seen)
+	}
+
+	// Caches written by other versions are discarded
+	if err := os.WriteFile(filename, []byte(`{"version":0,"feeds":{"https://example.com/feed":{"etag":"x","feed":{}}}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err = LoadFeedCache(filename)
+	if err != nil {
+		t.Fatalf("Failed to load feed cache: %v", err)
+	}
+	if len(loaded.Entries()) != 0 {
+		t.Errorf("Expected outdated cache to be discarded, got %+v", loaded.Entries())
+	}
+}
+
+func TestCredentialStore(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "credentials.json")
+	t.Setenv("RSSS_TEST_SECRET", "from-env")
+
+	store := NewCredentialStore()
+	if err := store.SetSecret("jira", "alice", "hunter2"); !errors.Is(err, ErrCredentialsLocked) {
+		t.Fatalf("Expected ErrCredentialsLocked before unlocking, got %v", err)
+	}
+	if err := store.Unlock("correct horse"); err != nil {
+		t.Fatalf("Failed to set passphrase: %v", err)
+	}
+	if err := store.SetSecret("jira", "alice", "hunter2"); err != nil {
+		t.Fatalf("Failed to store secret: %v", err)
+	}
+	store.SetEnv("ci", "", "RSSS_TEST_SECRET")
+	store.SetCommand("pass", "bob", "printf 'from-command\\nsecond line\\n'")
+	if err := store.Save(filename); err != nil {
+		t.Fatalf("Failed to save credentials: %v", err)
+	}
+
+	info, err := os.Stat(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("Expected credentials file mode 0600, got %v", info.Mode().Perm())
+	}
+	data, _ := os.ReadFile(filename)
+	if strings.Contains(string(data), "hunter2") {
+		t.Error("Expected secret to be encrypted at rest")
+	}
+
+	loaded, err := LoadCredentialStore(filename)
+	if err != nil {
+		t.Fatalf("Failed to load credentials: %v", err)
+	}
+	if !loaded.Locked() {
+		t.Error("Expected loaded store to be locked")
+	}
+	if _, _, err := loaded.Credential("jira"); !errors.Is(err, ErrCredentialsLocked) {
+		t.Errorf("Expected ErrCredentialsLocked for encrypted secret, got %v", err)
+	}
+
+	// Env and command credentials work without the passphrase
+	if _, secret, err := loaded.Credential("ci"); err != nil || secret != "from-env" {
+		t.Errorf("Expected secret from env, got %q, %v", secret, err)
+	}
+	if user, secret, err := loaded.Credential("pass"); err != nil || user != "bob" || secret != "from-command" {
+		t.Errorf("Expected bob/from-command, got %q/%q, %v", user, secret, err)
+	}
+
+	if err := loaded.Unlock("wrong"); !errors.Is(err, ErrWrongPassphrase) {
+		t.Errorf("Expected ErrWrongPassphrase, got %v", err)
+	}
+	if err := loaded.Unlock("correct horse"); err != nil {
+		t.Fatalf("Failed to unlock: %v", err)
+	}
+	if user, secret, err := loaded.Credential("jira"); err != nil || user != "alice" || secret != "hunter2" {
+		t.Errorf("Expected alice/hunter2, got %q/%q, %v", user, secret, err)
+	}
+	if _, _, err := loaded.Credential("missing"); err == nil {
+		t.Error("Expected error for unknown credential")
+	}
+}
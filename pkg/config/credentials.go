@@ -0,0 +1,337 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrCredentialsLocked is returned when an encrypted credential is needed
+// before the store has been unlocked with its passphrase
+var ErrCredentialsLocked = errors.New("credential store is locked")
+
+// ErrWrongPassphrase is returned when unlocking the store fails
+var ErrWrongPassphrase = errors.New("wrong passphrase")
+
+const (
+	credentialsVersion = 1
+
+	// pbkdf2Iterations follows the OWASP recommendation for PBKDF2-HMAC-SHA256
+	pbkdf2Iterations = 600000
+	keyLength        = 32
+
+	// passphraseCheck is encrypted with the key to verify the passphrase
+	passphraseCheck = "rsss credentials"
+
+	// commandTimeout limits how long a credential command may run
+	commandTimeout = 30 * time.Second
+)
+
+// Credential is a named secret that feeds can reference. The secret is
+// either stored encrypted in the credentials file, read from an environment
+// variable, or printed by a command such as `pass show name`, whose first
+// line of output is used.
+type Credential struct {
+	Username  string `json:"username,omitempty"`
+	Encrypted []byte `json:"encrypted,omitempty"`
+	Env       string `json:"env,omitempty"`
+	Command   string `json:"command,omitempty"`
+}
+
+// credentialsFile is the on-disk format of the credential store
+type credentialsFile struct {
+	Version     int                   `json:"version"`
+	Salt        []byte                `json:"salt,omitempty"`
+	Iterations  int                   `json:"iterations,omitempty"`
+	Check       []byte                `json:"check,omitempty"`
+	Credentials map[string]Credential `json:"credentials"`
+}
+
+// CredentialStore holds named credentials for authenticated feeds. Encrypted
+// secrets use AES-256-GCM with a key derived from a passphrase with PBKDF2,
+// and can only be read after Unlock. It is safe for concurrent use.
+type CredentialStore struct {
+	mu   sync.RWMutex
+	file credentialsFile
+	key  []byte
+}
+
+// NewCredentialStore creates an empty credential store
+func NewCredentialStore() *CredentialStore {
+	return &CredentialStore{file: credentialsFile{
+		Version:     credentialsVersion,
+		Credentials: make(map[string]Credential),
+	}}
+}
+
+// LoadCredentialStore loads the credential store from file
+func LoadCredentialStore(filename string) (*CredentialStore, error) {
+	store := NewCredentialStore()
+
+	if _, err := os.Stat(filename); os.IsNotExist(err) {
+		return store, nil
+	}
+
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return store, err
+	}
+
+	var loaded credentialsFile
+	if err := json.Unmarshal(data, &loaded); err != nil {
+		return store, fmt.Errorf("failed to parse credentials: %w", err)
+	}
+	if loaded.Version != credentialsVersion {
+		return store, fmt.Errorf("unsupported credentials version %d", loaded.Version)
+	}
+	if loaded.Credentials == nil {
+		loaded.Credentials = make(map[string]Credential)
+	}
+
+	store.file = loaded
+	return store, nil
+}
+
+// Save saves the credential store to file, readable only by the user
+func (s *CredentialStore) Save(filename string) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	dir := filepath.Dir(filename)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(s.file, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(filename, data, 0600)
+}
+
+// HasPassphrase reports whether the store has been protected with a passphrase
+func (s *CredentialStore) HasPassphrase() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return len(s.file.Salt) > 0
+}
+
+// Locked reports whether the store holds encrypted secrets that cannot be
+// read until it is unlocked
+func (s *CredentialStore) Locked() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return len(s.file.Salt) > 0 && s.key == nil
+}
+
+// Unlock derives the key from the passphrase so that encrypted secrets can be
+// read and stored. A store without a passphrase is protected with this one.
+func (s *CredentialStore) Unlock(passphrase string) error {
+	if passphrase == "" {
+		return errors.New("passphrase must not be empty")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.file.Salt) == 0 {
+		salt := make([]byte, 16)
+		if _, err := rand.Read(salt); err != nil {
+			return err
+		}
+		key, err := pbkdf2.Key(sha256.New, passphrase, salt, pbkdf2Iterations, keyLength)
+		if err != nil {
+			return err
+		}
+		check, err := seal(key, []byte(passphraseCheck))
+		if err != nil {
+			return err
+		}
+		s.file.Salt = salt
+		s.file.Iterations = pbkdf2Iterations
+		s.file.Check = check
+		s.key = key
+		return nil
+	}
+
+	key, err := pbkdf2.Key(sha256.New, passphrase, s.file.Salt, s.file.Iterations, keyLength)
+	if err != nil {
+		return err
+	}
+	if check, err := open(key, s.file.Check); err != nil || string(check) != passphraseCheck {
+		return ErrWrongPassphrase
+	}
+	s.key = key
+	return nil
+}
+
+// Names returns the names of all credentials in sorted order
+func (s *CredentialStore) Names() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	names := make([]string, 0, len(s.file.Credentials))
+	for name := range s.file.Credentials {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Get returns the stored definition of a credential
+func (s *CredentialStore) Get(name string) (Credential, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	cred, ok := s.file.Credentials[name]
+	return cred, ok
+}
+
+// SetSecret encrypts and stores a secret. The store must be unlocked.
+func (s *CredentialStore) SetSecret(name, username, secret string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.key == nil {
+		return ErrCredentialsLocked
+	}
+	encrypted, err := seal(s.key, []byte(secret))
+	if err != nil {
+		return err
+	}
+	s.file.Credentials[name] = Credential{Username: username, Encrypted: encrypted}
+	return nil
+}
+
+// SetEnv stores a credential whose secret is read from an environment variable
+func (s *CredentialStore) SetEnv(name, username, env string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.file.Credentials[name] = Credential{Username: username, Env: env}
+}
+
+// SetCommand stores a credential whose secret is printed by a shell command
+func (s *CredentialStore) SetCommand(name, username, command string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.file.Credentials[name] = Credential{Username: username, Command: command}
+}
+
+// Remove deletes a credential
+func (s *CredentialStore) Remove(name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.file.Credentials, name)
+}
+
+// Credential returns the username and secret of a named credential. It
+// implements rss.CredentialResolver.
+func (s *CredentialStore) Credential(name string) (string, string, error) {
+	s.mu.RLock()
+	cred, ok := s.file.Credentials[name]
+	key := s.key
+	s.mu.RUnlock()
+
+	if !ok {
+		return "", "", fmt.Errorf("unknown credential %q", name)
+	}
+
+	switch {
+	case cred.Env != "":
+		secret, ok := os.LookupEnv(cred.Env)
+		if !ok {
+			return "", "", fmt.Errorf("credential %q: environment variable %s is not set", name, cred.Env)
+		}
+		return cred.Username, secret, nil
+	case cred.Command != "":
+		secret, err := runCredentialCommand(cred.Command)
+		if err != nil {
+			return "", "", fmt.Errorf("credential %q: %w", name, err)
+		}
+		return cred.Username, secret, nil
+	default:
+		if key == nil {
+			return "", "", fmt.Errorf("credential %q: %w", name, ErrCredentialsLocked)
+		}
+		secret, err := open(key, cred.Encrypted)
+		if err != nil {
+			return "", "", fmt.Errorf("credential %q: failed to decrypt: %w", name, err)
+		}
+		return cred.Username, string(secret), nil
+	}
+}
+
+// runCredentialCommand runs a shell command and returns the first line of its output
+func runCredentialCommand(command string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), commandTimeout)
+	defer cancel()
+
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "sh", "-c", command)
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("command failed: %w: %s", err, msg)
+		}
+		return "", fmt.Errorf("command failed: %w", err)
+	}
+
+	line, _, _ := strings.Cut(string(output), "\n")
+	return strings.TrimRight(line, "\r"), nil
+}
+
+// seal encrypts plaintext with AES-GCM, prefixing the random nonce
+func seal(key, plaintext []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return gcm.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+// open decrypts data produced by seal
+func open(key, data []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < gcm.NonceSize() {
+		return nil, errors.New("ciphertext too short")
+	}
+	nonce, ciphertext := data[:gcm.NonceSize()], data[gcm.NonceSize():]
+	return gcm.Open(nil, nonce, ciphertext, nil)
+}
+
+// newGCM creates an AES-GCM cipher for the key
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
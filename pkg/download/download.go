@@ -0,0 +1,357 @@
+package download
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Status represents the state of a download
+type Status int
+
+const (
+	StatusQueued Status = iota
+	StatusDownloading
+	StatusCompleted
+	StatusFailed
+)
+
+// String returns a human-readable name for the status
+func (s Status) String() string {
+	switch s {
+	case StatusQueued:
+		return "queued"
+	case StatusDownloading:
+		return "downloading"
+	case StatusCompleted:
+		return "completed"
+	case StatusFailed:
+		return "failed"
+	default:
+		return "unknown"
+	}
+}
+
+// progressInterval limits how often progress updates are sent while downloading
+const progressInterval = 250 * time.Millisecond
+
+// DefaultStallTimeout is how long a download may wait for the server to send
+// more data before it is abandoned, so a stalled server cannot hold up the queue
+const DefaultStallTimeout = 30 * time.Second
+
+// Progress describes the state of a single download
+type Progress struct {
+	URL        string
+	Path       string
+	Status     Status
+	Downloaded int64
+	Total      int64 // -1 when the server does not report a size
+	Err        error
+}
+
+// Percent returns the completion percentage, or -1 if the size is unknown
+func (p Progress) Percent() int {
+	if p.Total <= 0 {
+		return -1
+	}
+	return int(p.Downloaded * 100 / p.Total)
+}
+
+// Manager downloads files one at a time into a directory. Partially
+// downloaded files are kept with a .part suffix and resumed with HTTP range
+// requests the next time they are queued.
+type Manager struct {
+	dir          string
+	httpClient   *http.Client
+	stallTimeout time.Duration
+
+	mu        sync.Mutex
+	downloads map[string]*Progress
+	queue     chan string
+	updates   chan Progress
+	startOnce sync.Once
+}
+
+// NewManager creates a download manager that saves files under dir, using
+// transport to reach servers. A nil transport uses http.DefaultTransport.
+func NewManager(dir string, transport http.RoundTripper) *Manager {
+	return &Manager{
+		dir:          dir,
+		httpClient:   &http.Client{Transport: transport},
+		stallTimeout: DefaultStallTimeout,
+		downloads:    make(map[string]*Progress),
+		queue:        make(chan string, 100),
+		updates:      make(chan Progress, 100),
+	}
+}
+
+// Updates returns the channel on which progress updates are delivered
+func (m *Manager) Updates() <-chan Progress {
+	return m.updates
+}
+
+// Path returns the destination path for a URL saved into the given subdirectory
+func (m *Manager) Path(rawURL, subdir string) string {
+	return filepath.Join(m.dir, sanitize(subdir), fileName(rawURL))
+}
+
+// Get returns the progress of a download and whether it is known to the manager
+func (m *Manager) Get(rawURL string) (Progress, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	p, ok := m.downloads[rawURL]
+	if !ok {
+		return Progress{}, false
+	}
+	return *p, true
+}
+
+// Active returns the number of downloads that are queued or in progress
+func (m *Manager) Active() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	active := 0
+	for _, p := range m.downloads {
+		if p.Status == StatusQueued || p.Status == StatusDownloading {
+			active++
+		}
+	}
+	return active
+}
+
+// Enqueue adds a URL to the download queue, saving it into the given
+// subdirectory of the download directory. It returns the destination path.
+func (m *Manager) Enqueue(rawURL, subdir string) (string, error) {
+	dest := m.Path(rawURL, subdir)
+
+	m.mu.Lock()
+	if p, ok := m.downloads[rawURL]; ok && (p.Status == StatusQueued || p.Status == StatusDownloading) {
+		m.mu.Unlock()
+		return p.Path, nil
+	}
+	progress := &Progress{URL: rawURL, Path: dest, Status: StatusQueued, Total: -1}
+	m.downloads[rawURL] = progress
+	m.mu.Unlock()
+
+	m.startOnce.Do(func() { go m.run() })
+
+	select {
+	case m.queue <- rawURL:
+		return dest, nil
+	default:
+		err := errors.New("download queue is full")
+		m.mu.Lock()
+		progress.Status = StatusFailed
+		progress.Err = err
+		m.mu.Unlock()
+		return "", err
+	}
+}
+
+// run processes queued downloads sequentially
+func (m *Manager) run() {
+	for rawURL := range m.queue {
+		m.finish(rawURL, m.download(rawURL))
+	}
+}
+
+// download fetches a single URL, resuming from a partial file if one exists
+func (m *Manager) download(rawURL string) error {
+	m.mu.Lock()
+	progress := m.downloads[rawURL]
+	dest := progress.Path
+	m.mu.Unlock()
+
+	if _, err := os.Stat(dest); err == nil {
+		// Already downloaded
+		return nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		return err
+	}
+
+	partial := dest + ".part"
+	var offset int64
+	if info, err := os.Stat(partial); err == nil {
+		offset = info.Size()
+	}
+
+	// The request is cancelled when no data arrives for the stall timeout
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var stalled atomic.Bool
+	timer := time.AfterFunc(m.stallTimeout, func() {
+		stalled.Store(true)
+		cancel()
+	})
+	defer timer.Stop()
+	stallErr := func(err error) error {
+		if stalled.Load() {
+			return fmt.Errorf("no data received for %v", m.stallTimeout)
+		}
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return err
+	}
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	}
+
+	resp, err := m.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to download: %w", stallErr(err))
+	}
+	defer resp.Body.Close()
+
+	flags := os.O_CREATE | os.O_WRONLY
+	switch resp.StatusCode {
+	case http.StatusOK:
+		// The server ignored the range request, so start over
+		offset = 0
+		flags |= os.O_TRUNC
+	case http.StatusPartialContent:
+		flags |= os.O_APPEND
+	case http.StatusRequestedRangeNotSatisfiable:
+		// The partial file already holds the whole body
+		return os.Rename(partial, dest)
+	default:
+		return fmt.Errorf("HTTP error: %d", resp.StatusCode)
+	}
+
+	file, err := os.OpenFile(partial, flags, 0644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	total := int64(-1)
+	if resp.ContentLength >= 0 {
+		total = offset + resp.ContentLength
+	}
+	m.update(rawURL, func(p *Progress) {
+		p.Status = StatusDownloading
+		p.Downloaded = offset
+		p.Total = total
+	}, true)
+
+	writer := &progressWriter{
+		written: offset,
+		report: func(written int64) {
+			m.update(rawURL, func(p *Progress) { p.Downloaded = written }, false)
+		},
+		received: func() { timer.Reset(m.stallTimeout) },
+	}
+	if _, err := io.Copy(file, io.TeeReader(resp.Body, writer)); err != nil {
+		return fmt.Errorf("download interrupted: %w", stallErr(err))
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(partial, dest)
+}
+
+// finish records the final state of a download
+func (m *Manager) finish(rawURL string, err error) {
+	m.update(rawURL, func(p *Progress) {
+		if err != nil {
+			p.Status = StatusFailed
+			p.Err = err
+			return
+		}
+		p.Status = StatusCompleted
+		if p.Total > 0 {
+			p.Downloaded = p.Total
+		}
+	}, true)
+}
+
+// update modifies a download's progress and publishes the new state
+func (m *Manager) update(rawURL string, fn func(p *Progress), important bool) {
+	m.mu.Lock()
+	p := m.downloads[rawURL]
+	fn(p)
+	snapshot := *p
+	m.mu.Unlock()
+
+	m.send(snapshot, important)
+}
+
+// send publishes a progress update. Intermediate updates are dropped when
+// nobody is listening, while state changes always get delivered.
+func (m *Manager) send(p Progress, important bool) {
+	if important {
+		m.updates <- p
+		return
+	}
+	select {
+	case m.updates <- p:
+	default:
+	}
+}
+
+// progressWriter counts bytes written and reports them at most every
+// progressInterval. received is called whenever data arrives.
+type progressWriter struct {
+	written    int64
+	lastReport time.Time
+	report     func(written int64)
+	received   func()
+}
+
+func (w *progressWriter) Write(p []byte) (int, error) {
+	w.written += int64(len(p))
+	w.received()
+	if time.Since(w.lastReport) >= progressInterval {
+		w.lastReport = time.Now()
+		w.report(w.written)
+	}
+	return len(p), nil
+}
+
+// fileName derives a file name from the last path segment of a URL. A short
+// hash of the URL keeps episodes with the same segment, e.g. audio.mp3 in
+// different directories, from overwriting each other.
+func fileName(rawURL string) string {
+	sum := sha1.Sum([]byte(rawURL))
+	if u, err := url.Parse(rawURL); err == nil {
+		if name := sanitize(path.Base(u.Path)); name != "" && name != "_" {
+			ext := path.Ext(name)
+			return strings.TrimSuffix(name, ext) + "-" + hex.EncodeToString(sum[:4]) + ext
+		}
+	}
+	return hex.EncodeToString(sum[:8])
+}
+
+// sanitize replaces characters that are unsafe in file names
+func sanitize(name string) string {
+	name = strings.TrimSpace(name)
+	if name == "." || name == ".." {
+		return "_"
+	}
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case '/', '\\', ':', '*', '?', '"', '<', '>', '|':
+			return '_'
+		}
+		return r
+	}, name)
+}
@@ -0,0 +1,194 @@
+package download
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// waitFor reads progress updates until the download reaches a final state
+func waitFor(t *testing.T, m *Manager) Progress {
+	t.Helper()
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case p := <-m.Updates():
+			if p.Status == StatusCompleted || p.Status == StatusFailed {
+				return p
+			}
+		case <-timeout:
+			t.Fatal("Timed out waiting for download")
+		}
+	}
+}
+
+func TestDownload(t *testing.T) {
+	content := strings.Repeat("podcast audio ", 100)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.ServeContent(w, r, "episode.mp3", time.Now(), strings.NewReader(content))
+	}))
+	defer server.Close()
+
+	manager := NewManager(t.TempDir(), nil)
+	dest, err := manager.Enqueue(server.URL+"/media/episode.mp3", "My Podcast")
+	if err != nil {
+		t.Fatalf("Enqueue returned error: %v", err)
+	}
+
+	if name := filepath.Base(dest); !strings.HasPrefix(name, "episode-") || filepath.Ext(name) != ".mp3" || filepath.Base(filepath.Dir(dest)) != "My Podcast" {
+		t.Errorf("Unexpected destination path %s", dest)
+	}
+
+	p := waitFor(t, manager)
+	if p.Err != nil {
+		t.Fatalf("Download failed: %v", p.Err)
+	}
+
+	data, err := os.ReadFile(dest)
+	if err != nil {
+		t.Fatalf("Failed to read downloaded file: %v", err)
+	}
+	if string(data) != content {
+		t.Error("Downloaded content does not match")
+	}
+	if p.Percent() != 100 {
+		t.Errorf("Expected 100%% progress, got %d", p.Percent())
+	}
+}
+
+func TestDownloadResume(t *testing.T) {
+	content := strings.Repeat("0123456789", 50)
+	var rangeHeader string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rangeHeader = r.Header.Get("Range")
+		http.ServeContent(w, r, "episode.mp3", time.Now(), strings.NewReader(content))
+	}))
+	defer server.Close()
+
+	manager := NewManager(t.TempDir(), nil)
+	rawURL := server.URL + "/episode.mp3"
+	dest := manager.Path(rawURL, "")
+
+	// Simulate an interrupted download
+	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(dest+".part", []byte(content[:120]), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := manager.Enqueue(rawURL, ""); err != nil {
+		t.Fatalf("Enqueue returned error: %v", err)
+	}
+	if p := waitFor(t, manager); p.Err != nil {
+		t.Fatalf("Download failed: %v", p.Err)
+	}
+
+	if rangeHeader != "bytes=120-" {
+		t.Errorf("Expected range request from byte 120, got '%s'", rangeHeader)
+	}
+
+	data, err := os.ReadFile(dest)
+	if err != nil {
+		t.Fatalf("Failed to read downloaded file: %v", err)
+	}
+	if string(data) != content {
+		t.Error("Resumed content does not match")
+	}
+	if _, err := os.Stat(dest + ".part"); !os.IsNotExist(err) {
+		t.Error("Expected partial file to be removed")
+	}
+}
+
+func TestDownloadHTTPError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	manager := NewManager(t.TempDir(), nil)
+	if _, err := manager.Enqueue(server.URL+"/missing.mp3", ""); err != nil {
+		t.Fatalf("Enqueue returned error: %v", err)
+	}
+
+	p := waitFor(t, manager)
+	if p.Status != StatusFailed || p.Err == nil {
+		t.Errorf("Expected failed download, got %+v", p)
+	}
+}
+
+func TestDownloadStalled(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Length", "1000")
+		w.Write([]byte("partial"))
+		w.(http.Flusher).Flush()
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+
+	manager := NewManager(t.TempDir(), nil)
+	manager.stallTimeout = 100 * time.Millisecond
+	if _, err := manager.Enqueue(server.URL+"/stalled.mp3", ""); err != nil {
+		t.Fatalf("Enqueue returned error: %v", err)
+	}
+
+	p := waitFor(t, manager)
+	if p.Status != StatusFailed || p.Err == nil || !strings.Contains(p.Err.Error(), "no data received") {
+		t.Errorf("Expected the stalled download to fail, got %+v", p)
+	}
+}
+
+func TestDownloadTransport(t *testing.T) {
+	var proxied []string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied = append(proxied, r.URL.String())
+		w.Write([]byte("audio"))
+	}))
+	defer proxy.Close()
+
+	proxyURL, err := url.Parse(proxy.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	manager := NewManager(t.TempDir(), &http.Transport{Proxy: http.ProxyURL(proxyURL)})
+
+	// Only reachable through the proxy
+	if _, err := manager.Enqueue("http://media.example.invalid/episode.mp3", ""); err != nil {
+		t.Fatalf("Enqueue returned error: %v", err)
+	}
+	if p := waitFor(t, manager); p.Err != nil {
+		t.Fatalf("Download failed: %v", p.Err)
+	}
+	if len(proxied) != 1 || proxied[0] != "http://media.example.invalid/episode.mp3" {
+		t.Errorf("Expected the download to go through the proxy, got %v", proxied)
+	}
+}
+
+func TestPathUnique(t *testing.T) {
+	manager := NewManager(t.TempDir(), nil)
+	urls := []string{
+		"https://example.com/123/audio.mp3",
+		"https://example.com/124/audio.mp3",
+		"https://example.com/episodes/1/download",
+		"https://example.com/episodes/2/download",
+	}
+
+	seen := make(map[string]string)
+	for _, rawURL := range urls {
+		dest := manager.Path(rawURL, "")
+		if other, ok := seen[dest]; ok {
+			t.Errorf("%s and %s both map to %s", other, rawURL, dest)
+		}
+		seen[dest] = rawURL
+	}
+	if dest := manager.Path(urls[0], ""); dest != manager.Path(urls[0], "") || filepath.Ext(dest) != ".mp3" {
+		t.Errorf("Expected a stable path keeping the extension, got %s", dest)
+	}
+}
@@ -0,0 +1,164 @@
+package rss
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+	"strings"
+)
+
+// Atom represents the root element of an Atom 1.0 feed
+type Atom struct {
+	XMLName  xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	Base     string      `xml:"http://www.w3.org/XML/1998/namespace base,attr"`
+	Title    string      `xml:"title"`
+	Subtitle string      `xml:"subtitle"`
+	Links    []AtomLink  `xml:"link"`
+	Entries  []AtomEntry `xml:"entry"`
+}
+
+// AtomEntry represents an Atom entry
+type AtomEntry struct {
+	Base       string         `xml:"http://www.w3.org/XML/1998/namespace base,attr"`
+	ID         string         `xml:"id"`
+	Title      string         `xml:"title"`
+	Links      []AtomLink     `xml:"link"`
+	Updated    string         `xml:"updated"`
+	Published  string         `xml:"published"`
+	Summary    AtomText       `xml:"summary"`
+	Content    AtomText       `xml:"content"`
+	Authors    []AtomPerson   `xml:"author"`
+	Categories []AtomCategory `xml:"category"`
+}
+
+// AtomLink represents an Atom link element
+type AtomLink struct {
+	Href   string `xml:"href,attr"`
+	Rel    string `xml:"rel,attr"`
+	Type   string `xml:"type,attr"`
+	Length string `xml:"length,attr"`
+}
+
+// AtomText represents an Atom text construct such as summary or content
+type AtomText struct {
+	Type     string `xml:"type,attr"`
+	Text     string `xml:",chardata"`
+	InnerXML string `xml:",innerxml"`
+}
+
+// Value returns the text content, keeping the markup of inline XHTML
+func (t AtomText) Value() string {
+	if t.Type == "xhtml" {
+		return strings.TrimSpace(t.InnerXML)
+	}
+	return strings.TrimSpace(t.Text)
+}
+
+// AtomCategory represents an Atom category element
+type AtomCategory struct {
+	Term  string `xml:"term,attr"`
+	Label string `xml:"label,attr"`
+}
+
+// AtomPerson represents an Atom author or contributor
+type AtomPerson struct {
+	Name  string `xml:"name"`
+	Email string `xml:"email"`
+}
+
+// alternateLink returns the href of the alternate link, falling back to the first link
+func alternateLink(links []AtomLink) string {
+	for _, link := range links {
+		// A missing rel attribute means "alternate" per RFC 4287
+		if link.Rel == "" || link.Rel == "alternate" {
+			return link.Href
+		}
+	}
+	if len(links) > 0 {
+		return links[0].Href
+	}
+	return ""
+}
+
+// isAtom reports whether the document is an Atom feed
+func isAtom(contentType string, body []byte) bool {
+	return hasRootElement(body, "feed")
+}
+
+// parseAtom parses an Atom document into a Feed
+func parseAtom(body []byte) (*Feed, error) {
+	return decodeAtom(bytes.NewReader(body))
+}
+
+// decodeAtom parses an Atom document read from r into a Feed
+func decodeAtom(r io.Reader) (*Feed, error) {
+	var atom Atom
+	if err := decodeXML(r, &atom); err != nil {
+		return nil, err
+	}
+	return atom.toFeed(), nil
+}
+
+// toFeed converts the Atom document into the format-neutral Feed model
+func (a *Atom) toFeed() *Feed {
+	feed := &Feed{
+		Title:       a.Title,
+		Link:        alternateLink(a.Links),
+		Description: a.Subtitle,
+		base:        a.Base,
+	}
+
+	for _, entry := range a.Entries {
+		content := entry.Content.Value()
+		description := entry.Summary.Value()
+		if description == "" {
+			description = content
+		}
+
+		pubDate := entry.Published
+		if pubDate == "" {
+			pubDate = entry.Updated
+		}
+
+		var authors []string
+		for _, author := range entry.Authors {
+			if author.Name != "" {
+				authors = append(authors, author.Name)
+			} else if author.Email != "" {
+				authors = append(authors, author.Email)
+			}
+		}
+
+		var categories []string
+		for _, category := range entry.Categories {
+			if category.Label != "" {
+				categories = append(categories, category.Label)
+			} else if category.Term != "" {
+				categories = append(categories, category.Term)
+			}
+		}
+
+		var enclosures []Enclosure
+		for _, l := range entry.Links {
+			if l.Rel == "enclosure" {
+				enclosures = append(enclosures, Enclosure{URL: l.Href, Type: l.Type, Length: parseLength(l.Length)})
+			}
+		}
+
+		link := alternateLink(entry.Links)
+		feed.Entries = append(feed.Entries, Entry{
+			ID:          entryID(entry.ID, link, entry.Title, pubDate),
+			Title:       entry.Title,
+			Link:        link,
+			Description: description,
+			PubDate:     entryDate(pubDate),
+			Content:     content,
+			Author:      strings.Join(authors, ", "),
+			Categories:  categories,
+			Enclosures:  enclosures,
+			base:        entry.Base,
+		})
+	}
+
+	return feed
+}
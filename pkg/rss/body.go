@@ -0,0 +1,120 @@
+package rss
+
+import (
+	"bufio"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// DefaultMaxBodySize limits a response to 10 MiB after decompression
+const DefaultMaxBodySize = 10 << 20
+
+// ErrBodyTooLarge is returned when a response exceeds the client's max body size
+var ErrBodyTooLarge = errors.New("response body too large")
+
+// errContentEncoding marks responses whose compression cannot be decoded
+var errContentEncoding = errors.New("unsupported content encoding")
+
+// acceptEncoding lists the content encodings decoded by responseBody
+const acceptEncoding = "gzip, deflate"
+
+// SetMaxBodySize limits the size of a response after decompression. Values
+// below 1 select DefaultMaxBodySize.
+func (c *Client) SetMaxBodySize(limit int64) {
+	if limit < 1 {
+		limit = DefaultMaxBodySize
+	}
+	c.maxBodySize = limit
+}
+
+// responseBody returns the decompressed body of a response. Reading it fails
+// with ErrBodyTooLarge once more than the client's max body size is read.
+func (c *Client) responseBody(resp *http.Response) (io.ReadCloser, error) {
+	if resp.ContentLength > c.maxBodySize {
+		return nil, bodyTooLarge(c.maxBodySize)
+	}
+
+	body := &limitedBody{closers: []io.Closer{resp.Body}, remaining: c.maxBodySize, limit: c.maxBodySize}
+	encoding := strings.ToLower(strings.TrimSpace(resp.Header.Get("Content-Encoding")))
+	switch encoding {
+	case "", "identity":
+		body.r = resp.Body
+	case "gzip", "x-gzip":
+		reader, err := gzip.NewReader(resp.Body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decompress response: %w", err)
+		}
+		body.r = reader
+		body.closers = append(body.closers, reader)
+	case "deflate":
+		// Some servers send raw DEFLATE data instead of the zlib format
+		buffered := bufio.NewReader(resp.Body)
+		var reader io.ReadCloser
+		if header, _ := buffered.Peek(2); isZlibHeader(header) {
+			zr, err := zlib.NewReader(buffered)
+			if err != nil {
+				return nil, fmt.Errorf("failed to decompress response: %w", err)
+			}
+			reader = zr
+		} else {
+			reader = flate.NewReader(buffered)
+		}
+		body.r = reader
+		body.closers = append(body.closers, reader)
+	default:
+		return nil, fmt.Errorf("%w %q", errContentEncoding, encoding)
+	}
+	return body, nil
+}
+
+// isZlibHeader reports whether data starts with a zlib stream header
+func isZlibHeader(header []byte) bool {
+	return len(header) == 2 && header[0]&0x0f == 8 && (uint16(header[0])<<8|uint16(header[1]))%31 == 0
+}
+
+// bodyTooLarge returns an ErrBodyTooLarge error mentioning the limit
+func bodyTooLarge(limit int64) error {
+	return fmt.Errorf("%w: limit is %d bytes", ErrBodyTooLarge, limit)
+}
+
+// limitedBody reads at most limit bytes, failing with ErrBodyTooLarge when
+// more are available, and closes all underlying readers
+type limitedBody struct {
+	r         io.Reader
+	closers   []io.Closer
+	remaining int64
+	limit     int64
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	if b.remaining <= 0 {
+		var probe [1]byte
+		n, err := b.r.Read(probe[:])
+		if n > 0 {
+			return 0, bodyTooLarge(b.limit)
+		}
+		return 0, err
+	}
+	if int64(len(p)) > b.remaining {
+		p = p[:b.remaining]
+	}
+	n, err := b.r.Read(p)
+	b.remaining -= int64(n)
+	return n, err
+}
+
+func (b *limitedBody) Close() error {
+	var err error
+	for i := len(b.closers) - 1; i >= 0; i-- {
+		if closeErr := b.closers[i].Close(); err == nil {
+			err = closeErr
+		}
+	}
+	return err
+}
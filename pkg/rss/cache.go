@@ -0,0 +1,144 @@
+package rss
+
+import (
+	"sync"
+	"time"
+)
+
+// CacheEntry holds the HTTP validators and the last parsed content of a feed
+type CacheEntry struct {
+	ETag         string `json:"etag,omitempty"`
+	LastModified string `json:"last_modified,omitempty"`
+	Feed         *Feed  `json:"feed"`
+}
+
+// Cache stores fetched feeds by URL so that unchanged feeds can be revalidated
+// with conditional requests instead of being downloaded again. A nil Cache
+// stores nothing. It is safe for concurrent use.
+type Cache struct {
+	mu        sync.RWMutex
+	entries   map[string]CacheEntry
+	firstSeen map[string]time.Time // When undated articles were first seen
+}
+
+// NewCache creates a cache holding the given entries
+func NewCache(entries map[string]CacheEntry) *Cache {
+	c := &Cache{entries: make(map[string]CacheEntry, len(entries)), firstSeen: make(map[string]time.Time)}
+	for url, entry := range entries {
+		if entry.Feed != nil {
+			c.entries[url] = entry
+		}
+	}
+	return c
+}
+
+// Get returns the cached entry for a feed URL
+func (c *Cache) Get(url string) (CacheEntry, bool) {
+	if c == nil {
+		return CacheEntry{}, false
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	entry, ok := c.entries[url]
+	return entry, ok
+}
+
+// Set stores the entry for a feed URL
+func (c *Cache) Set(url string, entry CacheEntry) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries[url] = entry
+}
+
+// Delete removes the entry for a feed URL
+func (c *Cache) Delete(url string) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.entries, url)
+}
+
+// Entries returns a copy of all cached entries keyed by feed URL
+func (c *Cache) Entries() map[string]CacheEntry {
+	entries := make(map[string]CacheEntry)
+	if c == nil {
+		return entries
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	for url, entry := range c.entries {
+		entries[url] = entry
+	}
+	return entries
+}
+
+// FirstSeen returns when the article with the given key was first seen,
+// recording now if it has not been seen before. A nil Cache returns now.
+func (c *Cache) FirstSeen(key string, now time.Time) time.Time {
+	if c == nil {
+		return now
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if t, ok := c.firstSeen[key]; ok {
+		return t
+	}
+	c.firstSeen[key] = now
+	return now
+}
+
+// FirstSeenTimes returns a copy of the recorded first-seen times
+func (c *Cache) FirstSeenTimes() map[string]time.Time {
+	times := make(map[string]time.Time)
+	if c == nil {
+		return times
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	for key, t := range c.firstSeen {
+		times[key] = t
+	}
+	return times
+}
+
+// SetFirstSeenTimes replaces the recorded first-seen times, e.g. with ones
+// loaded from file
+func (c *Cache) SetFirstSeenTimes(times map[string]time.Time) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.firstSeen = make(map[string]time.Time, len(times))
+	for key, t := range times {
+		c.firstSeen[key] = t
+	}
+}
+
+// pruneFirstSeen forgets articles that are not in keep and were first seen
+// before cutoff
+func (c *Cache) pruneFirstSeen(keep map[string]bool, cutoff time.Time) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for key, t := range c.firstSeen {
+		if !keep[key] && t.Before(cutoff) {
+			delete(c.firstSeen, key)
+		}
+	}
+}
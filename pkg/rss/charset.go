@@ -0,0 +1,94 @@
+package rss
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"mime"
+	"regexp"
+	"strings"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/htmlindex"
+	"golang.org/x/text/encoding/unicode"
+)
+
+// xmlDeclEncoding matches the encoding attribute of an XML declaration
+var xmlDeclEncoding = regexp.MustCompile(`^(\s*<\?xml[^>]*?encoding\s*=\s*["'])[^"']*(["'])`)
+
+// newXMLDecoder creates an XML decoder that transcodes documents declaring a
+// non-UTF-8 encoding in their XML declaration
+func newXMLDecoder(r io.Reader) *xml.Decoder {
+	decoder := xml.NewDecoder(r)
+	decoder.CharsetReader = charsetReader
+	return decoder
+}
+
+// decodeXML is xml.Unmarshal for a stream, with support for non-UTF-8 documents
+func decodeXML(r io.Reader, v any) error {
+	return newXMLDecoder(r).Decode(v)
+}
+
+// charsetReader returns a reader that converts input in the named charset to UTF-8
+func charsetReader(label string, input io.Reader) (io.Reader, error) {
+	enc, err := htmlindex.Get(label)
+	if err != nil {
+		return nil, fmt.Errorf("unsupported charset %q", label)
+	}
+	return enc.NewDecoder().Reader(input), nil
+}
+
+// toUTF8 converts a whole document to UTF-8 with utf8Reader. The XML
+// declaration is rewritten so that the document is not decoded twice.
+func toUTF8(contentType string, body []byte) ([]byte, error) {
+	r, known, err := utf8Reader(contentType, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	decoded, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode charset: %w", err)
+	}
+	if !known {
+		return decoded, nil
+	}
+	return xmlDeclEncoding.ReplaceAll(decoded, []byte("${1}UTF-8${2}")), nil
+}
+
+// utf8Reader converts a stream to UTF-8 when its encoding is known from a
+// byte order mark or the charset parameter of the Content-Type header, which
+// takes precedence over the XML declaration. It reports whether the encoding
+// is known, in which case the caller must rewrite the XML declaration so that
+// a stale encoding there does not decode the document a second time.
+func utf8Reader(contentType string, r io.Reader) (io.Reader, bool, error) {
+	buffered := bufio.NewReader(r)
+	bom, _ := buffered.Peek(3)
+
+	var enc encoding.Encoding
+	switch {
+	case bytes.HasPrefix(bom, []byte{0xFE, 0xFF}), bytes.HasPrefix(bom, []byte{0xFF, 0xFE}):
+		enc = unicode.UTF16(unicode.BigEndian, unicode.ExpectBOM)
+	case bytes.HasPrefix(bom, []byte{0xEF, 0xBB, 0xBF}):
+		buffered.Discard(3)
+		return buffered, true, nil
+	default:
+		_, params, err := mime.ParseMediaType(contentType)
+		if err != nil {
+			return buffered, false, nil
+		}
+		label := strings.ToLower(strings.TrimSpace(params["charset"]))
+		if label == "" {
+			return buffered, false, nil
+		}
+		if label == "utf-8" || label == "utf8" {
+			return buffered, true, nil
+		}
+		if enc, err = htmlindex.Get(label); err != nil {
+			return nil, false, fmt.Errorf("unsupported charset %q", label)
+		}
+	}
+
+	return enc.NewDecoder().Reader(buffered), true, nil
+}
@@ -0,0 +1,125 @@
+package rss
+
+import (
+	"regexp"
+	"strings"
+	"time"
+)
+
+// dateLayouts are tried in order by parseTime, after a leading weekday has
+// been removed and named time zones replaced by numeric offsets. Parsing
+// accepts fractional seconds after the seconds of any layout.
+var dateLayouts = []string{
+	// RFC 822 and RFC 1123, with two or four digit years and optional seconds
+	"2 Jan 2006 15:04:05 -0700",
+	"2 Jan 2006 15:04:05 -07:00",
+	"2 Jan 2006 15:04 -0700",
+	"2 Jan 2006 15:04:05",
+	"2 Jan 2006 15:04",
+	"2 Jan 06 15:04:05 -0700",
+	"2 Jan 06 15:04 -0700",
+	"2 Jan 06 15:04:05",
+	"2 Jan 06 15:04",
+	"2 January 2006 15:04:05 -0700",
+	"2 January 2006 15:04 -0700",
+	"2 January 2006 15:04:05",
+	"2 January 2006 15:04",
+	"2-Jan-06 15:04:05 -0700", // RFC 850
+	"2-Jan-2006 15:04:05 -0700",
+
+	// ISO 8601 and W3CDTF
+	"2006-01-02T15:04:05Z07:00",
+	"2006-01-02T15:04:05Z0700",
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04Z07:00",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04:05Z07:00",
+	"2006-01-02 15:04:05 -0700",
+	"2006-01-02 15:04:05 -07:00",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"20060102T150405Z0700",
+	"2006/01/02 15:04:05",
+	"2006/01/02 15:04",
+
+	// ANSI C, Unix date and similar
+	"Jan 2 15:04:05 2006",
+	"Jan 2 15:04:05 -0700 2006",
+	"Jan 2, 2006 15:04:05 -0700",
+	"Jan 2, 2006 15:04 -0700",
+	"Jan 2, 2006 3:04 PM",
+	"Jan 2, 2006 15:04:05",
+	"Jan 2, 2006 15:04",
+	"January 2, 2006 3:04 PM",
+	"January 2, 2006 15:04:05",
+	"January 2, 2006 15:04",
+
+	// Dates without a time
+	"2006-01-02",
+	"2006/01/02",
+	"2 Jan 2006",
+	"2 Jan 06",
+	"2 January 2006",
+	"Jan 2, 2006",
+	"Jan 2 2006",
+	"January 2, 2006",
+	"January 2 2006",
+}
+
+// zoneOffsets maps time zone abbreviations found in feeds to their offsets.
+// time.Parse only knows the offset of the local zone's abbreviations.
+var zoneOffsets = map[string]string{
+	"UT": "+0000", "UTC": "+0000", "GMT": "+0000", "Z": "+0000", "WET": "+0000",
+	"EST": "-0500", "EDT": "-0400", "CST": "-0600", "CDT": "-0500",
+	"MST": "-0700", "MDT": "-0600", "PST": "-0800", "PDT": "-0700",
+	"AKST": "-0900", "AKDT": "-0800", "HST": "-1000",
+	"BST": "+0100", "WEST": "+0100", "CET": "+0100", "CEST": "+0200",
+	"EET": "+0200", "EEST": "+0300", "MSK": "+0300", "IST": "+0530",
+	"SGT": "+0800", "HKT": "+0800", "AWST": "+0800", "JST": "+0900", "KST": "+0900",
+	"ACST": "+0930", "AEST": "+1000", "AEDT": "+1100", "NZST": "+1200", "NZDT": "+1300",
+}
+
+var (
+	// leadingWeekday matches a day name before a date, which feeds often get wrong
+	leadingWeekday = regexp.MustCompile(`^[A-Za-z]+\.?,?\s+`)
+	// dateComment matches a parenthesized comment such as "(UTC)"
+	dateComment = regexp.MustCompile(`\s*\([^)]*\)`)
+)
+
+// parseTime parses the many date formats found in feeds. It reports false
+// when the value is empty or cannot be parsed, rather than guessing.
+func parseTime(value string) (time.Time, bool) {
+	value = strings.TrimSpace(dateComment.ReplaceAllString(value, ""))
+	if value == "" {
+		return time.Time{}, false
+	}
+
+	fields := strings.Fields(value)
+	for i, field := range fields {
+		if offset, ok := zoneOffsets[strings.ToUpper(field)]; ok && i > 0 {
+			fields[i] = offset
+		}
+	}
+	normalized := strings.Join(fields, " ")
+
+	candidates := []string{normalized}
+	if withoutDay := leadingWeekday.ReplaceAllString(normalized, ""); withoutDay != normalized {
+		candidates = append(candidates, withoutDay)
+	}
+
+	for _, candidate := range candidates {
+		for _, layout := range dateLayouts {
+			if t, err := time.Parse(layout, candidate); err == nil {
+				return t, true
+			}
+		}
+	}
+	return time.Time{}, false
+}
+
+// entryDate returns the parsed date of an entry, or the zero time if it is
+// missing or cannot be parsed
+func entryDate(value string) time.Time {
+	t, _ := parseTime(value)
+	return t
+}
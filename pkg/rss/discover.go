@@ -0,0 +1,223 @@
+package rss
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/url"
+	"strings"
+	"sync"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// ErrHTMLPage is returned when a URL points to an HTML page instead of a feed.
+// Use Client.Discover to find the feeds the page advertises.
+var ErrHTMLPage = errors.New("URL points to an HTML page, not a feed")
+
+// DiscoveredFeed is a feed advertised by or found alongside an HTML page
+type DiscoveredFeed struct {
+	Title string
+	URL   string
+	Type  string
+}
+
+// feedLinkTypes are the link types that advertise a feed
+var feedLinkTypes = map[string]bool{
+	"application/rss+xml":   true,
+	"application/atom+xml":  true,
+	"application/feed+json": true,
+	"application/rdf+xml":   true,
+}
+
+// commonFeedPaths are probed when a page does not advertise any feeds
+var commonFeedPaths = []string{
+	"/feed",
+	"/rss",
+	"/rss.xml",
+	"/atom.xml",
+	"/feed.xml",
+	"/index.xml",
+	"/feed.json",
+}
+
+// isHTML reports whether a response is an HTML document
+func isHTML(contentType string, body []byte) bool {
+	if mediaType, _, err := mime.ParseMediaType(contentType); err == nil {
+		switch mediaType {
+		case "text/html", "application/xhtml+xml":
+			return true
+		}
+	}
+
+	head := bytes.ToLower(bytes.TrimSpace(body))
+	if len(head) > 512 {
+		head = head[:512]
+	}
+	return bytes.HasPrefix(head, []byte("<!doctype html")) || bytes.Contains(head, []byte("<html"))
+}
+
+// Discover returns the feeds available at a URL. If the URL is a feed itself
+// it is returned as the only result. For HTML pages, feeds advertised with
+// <link rel="alternate"> are returned, falling back to probing common feed
+// paths on the same site.
+func (c *Client) Discover(pageURL string) ([]DiscoveredFeed, error) {
+	return c.DiscoverContext(context.Background(), pageURL)
+}
+
+// DiscoverContext is like Discover but aborts its requests when ctx is done
+func (c *Client) DiscoverContext(ctx context.Context, pageURL string) ([]DiscoveredFeed, error) {
+	if IsLocalSource(pageURL) {
+		feed, err := c.fetchLocal(ctx, FeedInfo{URL: pageURL})
+		if err != nil {
+			return nil, err
+		}
+		return []DiscoveredFeed{{Title: feed.Title, URL: pageURL, Type: feed.Format}}, nil
+	}
+
+	resp, err := c.get(ctx, c.httpClient, pageURL, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	body, err := io.ReadAll(resp.body)
+	resp.body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	// Feeds are sometimes served as text/html, so only look for links in
+	// documents that are not a feed
+	feed, err := ParseFeed(resp.contentType, body)
+	if err == nil {
+		return []DiscoveredFeed{{Title: feed.Title, URL: pageURL, Type: feed.Format}}, nil
+	}
+	if !errors.Is(err, ErrUnknownFormat) || !isHTML(resp.contentType, body) {
+		return nil, err
+	}
+
+	if feeds := discoverLinks(pageURL, body); len(feeds) > 0 {
+		return feeds, nil
+	}
+
+	feeds := c.probeCommonPaths(ctx, pageURL)
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return feeds, nil
+}
+
+// discoverLinks extracts feeds advertised with <link rel="alternate"> tags,
+// resolving their URLs against the page URL and any <base href>
+func discoverLinks(pageURL string, body []byte) []DiscoveredFeed {
+	base, err := url.Parse(pageURL)
+	if err != nil {
+		return nil
+	}
+
+	var feeds []DiscoveredFeed
+	seen := make(map[string]bool)
+	tokenizer := html.NewTokenizer(bytes.NewReader(body))
+
+	for {
+		tokenType := tokenizer.Next()
+		if tokenType == html.ErrorToken {
+			return feeds
+		}
+		if tokenType != html.StartTagToken && tokenType != html.SelfClosingTagToken {
+			continue
+		}
+
+		token := tokenizer.Token()
+		switch token.DataAtom {
+		case atom.Base:
+			if href := attr(token, "href"); href != "" {
+				if resolved, err := base.Parse(href); err == nil {
+					base = resolved
+				}
+			}
+		case atom.Link:
+			if !hasToken(attr(token, "rel"), "alternate") {
+				continue
+			}
+			linkType := strings.ToLower(strings.TrimSpace(attr(token, "type")))
+			if mediaType, _, err := mime.ParseMediaType(linkType); err == nil {
+				linkType = mediaType
+			}
+			if !feedLinkTypes[linkType] {
+				continue
+			}
+
+			resolved, err := base.Parse(strings.TrimSpace(attr(token, "href")))
+			if err != nil || seen[resolved.String()] {
+				continue
+			}
+			seen[resolved.String()] = true
+			feeds = append(feeds, DiscoveredFeed{
+				Title: attr(token, "title"),
+				URL:   resolved.String(),
+				Type:  linkType,
+			})
+		case atom.Body:
+			// Feed links belong in the head
+			return feeds
+		}
+	}
+}
+
+// probeCommonPaths fetches well-known feed locations on the page's site in
+// parallel and returns those that parse as feeds, in probe order
+func (c *Client) probeCommonPaths(ctx context.Context, pageURL string) []DiscoveredFeed {
+	base, err := url.Parse(pageURL)
+	if err != nil {
+		return nil
+	}
+
+	results := make([]*DiscoveredFeed, len(commonFeedPaths))
+	var wg sync.WaitGroup
+	for i, path := range commonFeedPaths {
+		wg.Add(1)
+		go func(i int, path string) {
+			defer wg.Done()
+			candidate := base.ResolveReference(&url.URL{Path: path}).String()
+			// Candidates are not subscribed to, so keep them out of the cache
+			feed, _, err := c.fetch(ctx, FeedInfo{URL: candidate}, nil)
+			if err != nil {
+				return
+			}
+			results[i] = &DiscoveredFeed{Title: feed.Title, URL: candidate, Type: feed.Format}
+		}(i, path)
+	}
+	wg.Wait()
+
+	var feeds []DiscoveredFeed
+	for _, result := range results {
+		if result != nil {
+			feeds = append(feeds, *result)
+		}
+	}
+	return feeds
+}
+
+// attr returns the value of an HTML token attribute
+func attr(token html.Token, name string) string {
+	for _, a := range token.Attr {
+		if a.Key == name {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+// hasToken reports whether a space-separated attribute value contains a token
+func hasToken(value, token string) bool {
+	for _, field := range strings.Fields(strings.ToLower(value)) {
+		if field == token {
+			return true
+		}
+	}
+	return false
+}
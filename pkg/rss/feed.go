@@ -0,0 +1,251 @@
+package rss
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrUnknownFormat is returned when no registered parser recognizes a document
+var ErrUnknownFormat = errors.New("unknown feed format")
+
+// Feed is a format-neutral representation of a parsed feed
+type Feed struct {
+	Title       string
+	Link        string
+	Description string
+	Format      string
+	Entries     []Entry
+
+	base string // xml:base of the document, applied by ResolveURLs
+}
+
+// Entry is a format-neutral representation of a feed entry. Description
+// holds the summary or teaser, while Content holds the full body when the
+// feed provides one. ID is a stable identifier derived by entryID. PubDate
+// is zero when the feed gives no date or one that cannot be parsed.
+type Entry struct {
+	ID           string
+	Title        string
+	Link         string
+	Description  string
+	PubDate      time.Time
+	Content      string
+	Author       string
+	Categories   []string
+	Enclosures   []Enclosure
+	CommentsURL  string
+	CommentCount int
+	Duration     time.Duration
+	Episode      int
+	Image        string
+
+	base string // xml:base of the entry, applied by ResolveURLs
+}
+
+// SniffFunc reports whether a document with the given Content-Type and body
+// is in the format handled by a parser
+type SniffFunc func(contentType string, body []byte) bool
+
+// ParseFunc parses a document into a Feed
+type ParseFunc func(body []byte) (*Feed, error)
+
+// format is a registered feed format. Built-in formats can also decode a
+// stream, so that large documents are not buffered whole.
+type format struct {
+	name   string
+	sniff  SniffFunc
+	parse  ParseFunc
+	decode func(r io.Reader) (*Feed, error)
+}
+
+// sniffLen is how much of a stream is read to detect its format
+const sniffLen = 16 << 10
+
+var (
+	formatsMu sync.RWMutex
+	formats   []format
+)
+
+func init() {
+	formats = []format{
+		{name: "rss", sniff: isRSS, parse: parseRSS, decode: decodeRSS},
+		{name: "atom", sniff: isAtom, parse: parseAtom, decode: decodeAtom},
+		{name: "rdf", sniff: isRDF, parse: parseRDF, decode: decodeRDF},
+		{name: "json", sniff: isJSONFeed, parse: parseJSONFeed, decode: decodeJSONFeed},
+	}
+}
+
+// RegisterFormat registers a parser for a feed format. Formats are sniffed in
+// registration order, so the built-in formats take precedence.
+func RegisterFormat(name string, sniff SniffFunc, parse ParseFunc) {
+	formatsMu.Lock()
+	defer formatsMu.Unlock()
+
+	formats = append(formats, format{name: name, sniff: sniff, parse: parse})
+}
+
+// Formats returns the names of all registered feed formats
+func Formats() []string {
+	formatsMu.RLock()
+	defer formatsMu.RUnlock()
+
+	names := make([]string, len(formats))
+	for i, f := range formats {
+		names[i] = f.name
+	}
+	return names
+}
+
+// ParseFeed converts a document to UTF-8, detects its format and parses it
+// with the matching parser
+func ParseFeed(contentType string, body []byte) (*Feed, error) {
+	body, err := toUTF8(contentType, body)
+	if err != nil {
+		return nil, err
+	}
+	return parseDocument(contentType, body)
+}
+
+// ParseFeedReader is like ParseFeed but reads the document from r. The
+// built-in formats are decoded as the document is read; documents in other
+// formats are read whole first.
+func ParseFeedReader(contentType string, r io.Reader) (*Feed, error) {
+	feed, _, err := parseStream(contentType, r)
+	return feed, err
+}
+
+// parseStream implements ParseFeedReader. It also returns the start of the
+// document, converted to UTF-8, so that callers can inspect what was read.
+func parseStream(contentType string, r io.Reader) (*Feed, []byte, error) {
+	r, known, err := utf8Reader(contentType, r)
+	if err != nil {
+		return nil, nil, err
+	}
+	source := &readErrors{r: r}
+
+	head := make([]byte, sniffLen)
+	n, err := io.ReadFull(source, head)
+	head = head[:n]
+	complete := err == io.EOF || err == io.ErrUnexpectedEOF
+	if err != nil && !complete {
+		return nil, head, fmt.Errorf("failed to read response body: %w", err)
+	}
+	if known {
+		head = xmlDeclEncoding.ReplaceAll(head, []byte("${1}UTF-8${2}"))
+	}
+
+	f, ok := sniffFormat(contentType, head)
+	if complete || !ok || f.decode == nil {
+		rest, err := io.ReadAll(source)
+		if err != nil {
+			return nil, head, fmt.Errorf("failed to read response body: %w", err)
+		}
+		feed, err := parseDocument(contentType, append(head, rest...))
+		return feed, head, err
+	}
+
+	feed, err := f.decode(io.MultiReader(bytes.NewReader(head), source))
+	if source.err != nil {
+		return nil, head, fmt.Errorf("failed to read response body: %w", source.err)
+	}
+	if err != nil {
+		return nil, head, fmt.Errorf("failed to parse %s feed: %w", f.name, err)
+	}
+	feed.Format = f.name
+	return feed, head, nil
+}
+
+// readErrors records the first error other than io.EOF returned by a reader,
+// to tell read failures apart from malformed documents
+type readErrors struct {
+	r   io.Reader
+	err error
+}
+
+func (r *readErrors) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	if err != nil && err != io.EOF && r.err == nil {
+		r.err = err
+	}
+	return n, err
+}
+
+// sniffFormat returns the first registered format that recognizes a document
+func sniffFormat(contentType string, body []byte) (format, bool) {
+	formatsMu.RLock()
+	defer formatsMu.RUnlock()
+
+	for _, f := range formats {
+		if f.sniff(contentType, body) {
+			return f, true
+		}
+	}
+	return format{}, false
+}
+
+// parseDocument detects the format of a UTF-8 document and parses it
+func parseDocument(contentType string, body []byte) (*Feed, error) {
+	formatsMu.RLock()
+	registered := formats
+	formatsMu.RUnlock()
+
+	for _, f := range registered {
+		if !f.sniff(contentType, body) {
+			continue
+		}
+
+		feed, err := f.parse(body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s feed: %w", f.name, err)
+		}
+		feed.Format = f.name
+		return feed, nil
+	}
+
+	// Explain why an XML document could not be read, e.g. an unsupported charset
+	if _, err := rootElement(body); err != nil && bytes.HasPrefix(bytes.TrimSpace(body), []byte("<")) {
+		return nil, fmt.Errorf("%w: %v", ErrUnknownFormat, err)
+	}
+	return nil, ErrUnknownFormat
+}
+
+// rootElement returns the name of the first element in an XML document
+func rootElement(body []byte) (xml.Name, error) {
+	decoder := newXMLDecoder(bytes.NewReader(body))
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return xml.Name{}, err
+		}
+		if start, ok := token.(xml.StartElement); ok {
+			return start.Name, nil
+		}
+	}
+}
+
+// hasRootElement reports whether an XML document's root element has the given local name
+func hasRootElement(body []byte, local string) bool {
+	root, err := rootElement(body)
+	return err == nil && root.Local == local
+}
+
+// entryID derives a stable identifier for an entry, preferring the feed's own
+// GUID, then the link, and finally a hash of the title and raw date string
+func entryID(guid, link, title, date string) string {
+	if guid = strings.TrimSpace(guid); guid != "" {
+		return guid
+	}
+	if link = strings.TrimSpace(link); link != "" {
+		return link
+	}
+	sum := sha1.Sum([]byte(title + "\x00" + date))
+	return "sha1:" + hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,166 @@
+package rss
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"strings"
+	"time"
+)
+
+// JSONFeed represents a JSON Feed 1.1 document (https://www.jsonfeed.org/version/1.1/)
+type JSONFeed struct {
+	Version     string         `json:"version"`
+	Title       string         `json:"title"`
+	HomePageURL string         `json:"home_page_url"`
+	FeedURL     string         `json:"feed_url"`
+	Description string         `json:"description"`
+	Authors     []JSONAuthor   `json:"authors"`
+	Items       []JSONFeedItem `json:"items"`
+}
+
+// JSONFeedItem represents an item in a JSON Feed
+type JSONFeedItem struct {
+	ID            string           `json:"id"`
+	URL           string           `json:"url"`
+	ExternalURL   string           `json:"external_url"`
+	Title         string           `json:"title"`
+	ContentHTML   string           `json:"content_html"`
+	ContentText   string           `json:"content_text"`
+	Summary       string           `json:"summary"`
+	Image         string           `json:"image"`
+	DatePublished string           `json:"date_published"`
+	DateModified  string           `json:"date_modified"`
+	Authors       []JSONAuthor     `json:"authors"`
+	Author        *JSONAuthor      `json:"author"` // JSON Feed 1.0
+	Tags          []string         `json:"tags"`
+	Attachments   []JSONAttachment `json:"attachments"`
+}
+
+// JSONAuthor represents a JSON Feed author
+type JSONAuthor struct {
+	Name string `json:"name"`
+	URL  string `json:"url"`
+}
+
+// JSONAttachment represents a JSON Feed attachment
+type JSONAttachment struct {
+	URL         string  `json:"url"`
+	MimeType    string  `json:"mime_type"`
+	Title       string  `json:"title"`
+	SizeInBytes int64   `json:"size_in_bytes"`
+	Duration    float64 `json:"duration_in_seconds"`
+}
+
+// jsonFeedVersionPrefix starts the version URL of every JSON Feed version
+const jsonFeedVersionPrefix = "https://jsonfeed.org/version/"
+
+// isJSONFeed reports whether a response looks like a JSON Feed, using the
+// Content-Type header first and falling back to sniffing the body
+func isJSONFeed(contentType string, body []byte) bool {
+	if mediaType, _, err := mime.ParseMediaType(contentType); err == nil {
+		switch mediaType {
+		case "application/feed+json", "application/json":
+			return true
+		}
+	}
+	return bytes.HasPrefix(bytes.TrimSpace(body), []byte("{"))
+}
+
+// authorNames joins the names of the given authors
+func authorNames(authors []JSONAuthor) string {
+	var names []string
+	for _, author := range authors {
+		if author.Name != "" {
+			names = append(names, author.Name)
+		}
+	}
+	return strings.Join(names, ", ")
+}
+
+// parseJSONFeed parses a JSON Feed document into a Feed
+func parseJSONFeed(body []byte) (*Feed, error) {
+	return decodeJSONFeed(bytes.NewReader(body))
+}
+
+// decodeJSONFeed parses a JSON Feed document read from r into a Feed. Other
+// JSON documents, such as API errors, are rejected by their missing version.
+func decodeJSONFeed(r io.Reader) (*Feed, error) {
+	var feed JSONFeed
+	if err := json.NewDecoder(r).Decode(&feed); err != nil {
+		return nil, err
+	}
+	if !strings.HasPrefix(feed.Version, jsonFeedVersionPrefix) {
+		return nil, fmt.Errorf("%w: JSON document without a JSON Feed version", ErrUnknownFormat)
+	}
+	return feed.toFeed(), nil
+}
+
+// toFeed converts the JSON Feed document into the format-neutral Feed model
+func (f *JSONFeed) toFeed() *Feed {
+	feed := &Feed{
+		Title:       f.Title,
+		Link:        f.HomePageURL,
+		Description: f.Description,
+	}
+
+	for _, item := range f.Items {
+		link := item.URL
+		if link == "" {
+			link = item.ExternalURL
+		}
+
+		content := item.ContentHTML
+		if content == "" {
+			content = item.ContentText
+		}
+		description := item.Summary
+		if description == "" {
+			description = content
+		}
+
+		pubDate := item.DatePublished
+		if pubDate == "" {
+			pubDate = item.DateModified
+		}
+
+		author := authorNames(item.Authors)
+		if author == "" && item.Author != nil {
+			author = item.Author.Name
+		}
+		if author == "" {
+			author = authorNames(f.Authors)
+		}
+
+		var enclosures []Enclosure
+		var duration time.Duration
+		for _, attachment := range item.Attachments {
+			if duration == 0 && attachment.Duration > 0 {
+				duration = time.Duration(attachment.Duration * float64(time.Second))
+			}
+			enclosures = append(enclosures, Enclosure{
+				URL:    attachment.URL,
+				Type:   attachment.MimeType,
+				Length: attachment.SizeInBytes,
+			})
+		}
+
+		feed.Entries = append(feed.Entries, Entry{
+			ID:          entryID(item.ID, link, item.Title, pubDate),
+			Title:       item.Title,
+			Link:        link,
+			Description: description,
+			PubDate:     entryDate(pubDate),
+			Content:     content,
+			Author:      author,
+			Categories:  item.Tags,
+			Enclosures:  enclosures,
+			Duration:    duration,
+			Image:       item.Image,
+		})
+	}
+
+	return feed
+}
@@ -0,0 +1,148 @@
+package rss
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Authentication schemes supported by FeedAuth
+const (
+	AuthBasic  = "basic"
+	AuthBearer = "bearer"
+)
+
+// FeedAuth configures HTTP authentication for a feed. When Credential names
+// an entry in the client's credential store, its secret is used as the
+// password or token, and its username unless Username is set.
+type FeedAuth struct {
+	Type       string `json:"type"` // AuthBasic or AuthBearer
+	Username   string `json:"username,omitempty"`
+	Password   string `json:"password,omitempty"`
+	Token      string `json:"token,omitempty"`
+	Credential string `json:"credential,omitempty"`
+}
+
+// CredentialResolver looks up named credentials referenced by feeds
+type CredentialResolver interface {
+	Credential(name string) (username, secret string, err error)
+}
+
+// SetCredentials sets where credentials referenced by feeds are looked up
+func (c *Client) SetCredentials(resolver CredentialResolver) {
+	c.credentials = resolver
+}
+
+// envReference matches ${NAME} references to environment variables
+var envReference = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// expandEnv replaces ${NAME} references with the values of environment
+// variables, so that secrets can be kept out of the feeds file. Other uses of
+// $ are left alone. Referencing an unset variable is an error.
+func expandEnv(value string) (string, error) {
+	var missing []string
+	expanded := envReference.ReplaceAllStringFunc(value, func(ref string) string {
+		name := envReference.FindStringSubmatch(ref)[1]
+		v, ok := os.LookupEnv(name)
+		if !ok {
+			missing = append(missing, name)
+		}
+		return v
+	})
+	if len(missing) > 0 {
+		return "", fmt.Errorf("environment variable %s is not set", strings.Join(missing, ", "))
+	}
+	return expanded, nil
+}
+
+// requestHeader builds the extra request headers configured for a feed
+func (f FeedInfo) requestHeader(credentials CredentialResolver) (http.Header, error) {
+	header := make(http.Header)
+
+	for name, value := range f.Headers {
+		expanded, err := expandEnv(value)
+		if err != nil {
+			return nil, fmt.Errorf("header %s: %w", name, err)
+		}
+		header.Set(name, expanded)
+	}
+
+	if f.UserAgent != "" {
+		userAgent, err := expandEnv(f.UserAgent)
+		if err != nil {
+			return nil, fmt.Errorf("user agent: %w", err)
+		}
+		header.Set("User-Agent", userAgent)
+	}
+
+	if f.Auth != nil {
+		authorization, err := f.Auth.authorization(credentials)
+		if err != nil {
+			return nil, fmt.Errorf("auth: %w", err)
+		}
+		header.Set("Authorization", authorization)
+	}
+
+	if len(f.Cookies) > 0 {
+		names := make([]string, 0, len(f.Cookies))
+		for name := range f.Cookies {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		cookies := make([]string, 0, len(names))
+		for _, name := range names {
+			value, err := expandEnv(f.Cookies[name])
+			if err != nil {
+				return nil, fmt.Errorf("cookie %s: %w", name, err)
+			}
+			cookies = append(cookies, (&http.Cookie{Name: name, Value: value}).String())
+		}
+		header.Set("Cookie", strings.Join(cookies, "; "))
+	}
+
+	return header, nil
+}
+
+// authorization returns the Authorization header value for the configured scheme
+func (a *FeedAuth) authorization(credentials CredentialResolver) (string, error) {
+	scheme := strings.ToLower(a.Type)
+	if scheme != AuthBasic && scheme != AuthBearer {
+		return "", fmt.Errorf("unsupported auth type %q", a.Type)
+	}
+
+	username, err := expandEnv(a.Username)
+	if err != nil {
+		return "", err
+	}
+	secret := a.Password
+	if scheme == AuthBearer {
+		secret = a.Token
+	}
+	if secret, err = expandEnv(secret); err != nil {
+		return "", err
+	}
+
+	if a.Credential != "" {
+		if credentials == nil {
+			return "", fmt.Errorf("credential %q requested but no credential store is configured", a.Credential)
+		}
+		storedUsername, storedSecret, err := credentials.Credential(a.Credential)
+		if err != nil {
+			return "", err
+		}
+		if username == "" {
+			username = storedUsername
+		}
+		secret = storedSecret
+	}
+
+	if scheme == AuthBearer {
+		return "Bearer " + secret, nil
+	}
+	return "Basic " + base64.StdEncoding.EncodeToString([]byte(username+":"+secret)), nil
+}
@@ -1,17 +1,43 @@
 package rss
 
 import (
-	"encoding/xml"
+	"context"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"sort"
+	"strings"
+	"sync"
 	"time"
 )
 
+// Default limits for FetchMultipleFeeds
+const (
+	DefaultMaxConcurrency = 8
+	DefaultMaxPerHost     = 2
+)
+
+// firstSeenRetention is how long the first-seen time of an undated article is
+// remembered after it disappears from its feed
+const firstSeenRetention = 30 * 24 * time.Hour
+
 // Client handles RSS feed fetching and parsing
 type Client struct {
-	httpClient *http.Client
+	httpClient     *http.Client
+	cache          *Cache
+	maxConcurrency int
+	maxPerHost     int
+	maxBodySize    int64
+	commandTimeout time.Duration
+	retry          RetryPolicy
+	failures       failureTracker
+	credentials    CredentialResolver
+
+	transportMu sync.Mutex
+	transport   TransportOptions
+	feedClients map[string]*http.Client // Clients for feeds with their own transport options
 }
 
 // NewClient creates a new RSS client with the specified timeout
@@ -20,91 +46,468 @@ func NewClient(timeout time.Duration) *Client {
 		httpClient: &http.Client{
 			Timeout: timeout,
 		},
+		maxConcurrency: DefaultMaxConcurrency,
+		maxPerHost:     DefaultMaxPerHost,
+		maxBodySize:    DefaultMaxBodySize,
+		commandTimeout: DefaultCommandTimeout,
+		retry:          DefaultRetryPolicy,
+	}
+}
+
+// SetConcurrency limits how many feeds FetchMultipleFeeds fetches at once, in
+// total and from a single host. Values below 1 select the defaults.
+func (c *Client) SetConcurrency(maxConcurrency, maxPerHost int) {
+	if maxConcurrency < 1 {
+		maxConcurrency = DefaultMaxConcurrency
+	}
+	if maxPerHost < 1 {
+		maxPerHost = DefaultMaxPerHost
+	}
+	c.maxConcurrency = maxConcurrency
+	c.maxPerHost = maxPerHost
+}
+
+// SetCache makes the client revalidate feeds stored in cache with
+// If-None-Match and If-Modified-Since, reusing the cached feed when the
+// server answers 304 Not Modified
+func (c *Client) SetCache(cache *Cache) {
+	c.cache = cache
+}
+
+// Cache returns the client's feed cache, or nil if caching is disabled
+func (c *Client) Cache() *Cache {
+	return c.cache
+}
+
+// ErrFeedGone is reported for feeds marked dead after answering 410 Gone
+var ErrFeedGone = errors.New("feed is gone")
+
+// HTTPError is returned when a server answers with an unexpected status code
+type HTTPError struct {
+	StatusCode int
+	RetryAfter time.Duration // Delay requested by the server, 0 if none
+}
+
+func (e *HTTPError) Error() string {
+	return fmt.Sprintf("HTTP error: %d", e.StatusCode)
+}
+
+// FetchStatus describes the outcome of fetching a single feed
+type FetchStatus int
+
+const (
+	FetchOK FetchStatus = iota
+	FetchNotModified
+	FetchFailed
+	FetchSkipped // Not fetched because the feed keeps failing
+	FetchGone    // The server answered 410 Gone, or the feed is marked dead
+)
+
+// String returns a human-readable name for the status
+func (s FetchStatus) String() string {
+	switch s {
+	case FetchOK:
+		return "ok"
+	case FetchNotModified:
+		return "not modified"
+	case FetchFailed:
+		return "failed"
+	case FetchSkipped:
+		return "skipped"
+	case FetchGone:
+		return "gone"
+	default:
+		return "unknown"
+	}
+}
+
+// FetchResult reports how fetching a single feed went. Failures counts the
+// consecutive failed fetches, and a feed with failures is not fetched again
+// before NextAttempt. MovedTo is set when the feed was reached only through
+// permanent redirects, and is the URL the subscription should be updated to.
+type FetchResult struct {
+	Feed        FeedInfo
+	Status      FetchStatus
+	HTTPStatus  int // 0 when no response was received
+	Duration    time.Duration
+	ItemCount   int
+	Err         error
+	Failures    int
+	NextAttempt time.Time
+	MovedTo     string
+}
+
+// response is the result of fetching a URL. Unless notModified is set, the
+// caller must close body.
+type response struct {
+	statusCode   int
+	url          string // Final URL after redirects
+	contentType  string
+	body         io.ReadCloser
+	etag         string
+	lastModified string
+	notModified  bool
+	movedTo      string // Final URL if only permanent redirects were followed
+}
+
+// FetchFeed fetches the given URL and parses it with the matching registered format
+func (c *Client) FetchFeed(url string) (*Feed, error) {
+	return c.FetchFeedContext(context.Background(), url)
+}
+
+// FetchFeedContext is like FetchFeed but aborts the request when ctx is done
+func (c *Client) FetchFeedContext(ctx context.Context, url string) (*Feed, error) {
+	feed, _, err := c.fetch(ctx, FeedInfo{URL: url}, c.cache)
+	return feed, err
+}
+
+// fetch fetches and parses a feed with its configured request options,
+// revalidating and updating its entry in cache. The response is returned
+// whenever the server answered successfully. Local sources are not cached.
+func (c *Client) fetch(ctx context.Context, info FeedInfo, cache *Cache) (*Feed, *response, error) {
+	if IsLocalSource(info.URL) {
+		feed, err := c.fetchLocal(ctx, info)
+		if err != nil {
+			return nil, nil, err
+		}
+		feed.ResolveURLs(info.URL)
+		return feed, &response{}, nil
+	}
+
+	header, err := info.requestHeader(c.credentials)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid feed options: %w", err)
+	}
+	httpClient, err := c.clientFor(info)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid transport options: %w", err)
+	}
+
+	url := info.URL
+	cached, ok := cache.Get(url)
+	var validators *CacheEntry
+	if ok {
+		validators = &cached
+	}
+
+	resp, err := c.getWithRetry(ctx, httpClient, url, header, validators)
+	if err != nil {
+		return nil, nil, err
+	}
+	if resp.notModified {
+		return cached.Feed, resp, nil
+	}
+
+	feed, head, err := c.parseBody(ctx, info, resp.url, resp.contentType, resp.body)
+	resp.body.Close()
+	if err != nil {
+		if info.Filter == "" && info.Scrape == nil && errors.Is(err, ErrUnknownFormat) && isHTML(resp.contentType, head) {
+			return nil, resp, ErrHTMLPage
+		}
+		return nil, resp, err
+	}
+	feed.ResolveURLs(resp.url)
+
+	key := url
+	if resp.movedTo != "" {
+		// The subscription is expected to move, so cache under the new URL
+		cache.Delete(url)
+		key = resp.movedTo
 	}
+	if resp.etag != "" || resp.lastModified != "" {
+		cache.Set(key, CacheEntry{ETag: resp.etag, LastModified: resp.lastModified, Feed: feed})
+	} else {
+		cache.Delete(key)
+	}
+	return feed, resp, nil
 }
 
-// FetchFeed fetches and parses an RSS feed from the given URL
-func (c *Client) FetchFeed(url string) (*RSS, error) {
-	resp, err := c.httpClient.Get(url)
+// get fetches a URL, adding the given headers. When cached validators are
+// given the request is conditional, and a 304 Not Modified response is
+// reported as notModified. The body is decompressed and limited to the
+// client's max body size as it is read.
+func (c *Client) get(ctx context.Context, httpClient *http.Client, url string, header http.Header, cached *CacheEntry) (*response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch feed: %w", err)
+	}
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	if req.Header.Get("Accept-Encoding") == "" {
+		req.Header.Set("Accept-Encoding", acceptEncoding)
+	}
+	if cached != nil {
+		if cached.ETag != "" {
+			req.Header.Set("If-None-Match", cached.ETag)
+		}
+		if cached.LastModified != "" {
+			req.Header.Set("If-Modified-Since", cached.LastModified)
+		}
+	}
+
+	resp, err := httpClient.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("failed to fetch RSS feed: %w", err)
+		return nil, fmt.Errorf("failed to fetch feed: %w", err)
 	}
-	defer resp.Body.Close()
 
+	if resp.StatusCode == http.StatusNotModified && cached != nil {
+		resp.Body.Close()
+		return &response{statusCode: resp.StatusCode, notModified: true, movedTo: permanentRedirect(resp)}, nil
+	}
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("HTTP error: %d", resp.StatusCode)
+		resp.Body.Close()
+		return nil, &HTTPError{
+			StatusCode: resp.StatusCode,
+			RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()),
+		}
 	}
 
-	body, err := io.ReadAll(resp.Body)
+	body, err := c.responseBody(resp)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read response body: %w", err)
+		resp.Body.Close()
+		return nil, err
 	}
 
-	var rss RSS
-	if err := xml.Unmarshal(body, &rss); err != nil {
-		return nil, fmt.Errorf("failed to parse RSS: %w", err)
+	return &response{
+		statusCode:   resp.StatusCode,
+		url:          resp.Request.URL.String(),
+		contentType:  resp.Header.Get("Content-Type"),
+		body:         body,
+		etag:         resp.Header.Get("ETag"),
+		lastModified: resp.Header.Get("Last-Modified"),
+		movedTo:      permanentRedirect(resp),
+	}, nil
+}
+
+// permanentRedirect returns the final URL of a response that was reached
+// through permanent redirects only, or "" if there were none or any of them
+// was temporary
+func permanentRedirect(resp *http.Response) string {
+	final := resp.Request
+	if final == nil || final.Response == nil {
+		return ""
 	}
+	for req := final; req.Response != nil; req = req.Response.Request {
+		switch req.Response.StatusCode {
+		case http.StatusMovedPermanently, http.StatusPermanentRedirect:
+		default:
+			return ""
+		}
+	}
+	return final.URL.String()
+}
 
-	return &rss, nil
+// FetchMultipleFeeds fetches multiple feeds in parallel and returns all
+// articles sorted by date, along with a result for each feed in the order of
+// the feeds list. Articles with the same date keep the order of the feeds
+// list, so the result does not depend on which fetch finishes first. Feeds
+// that keep failing are skipped until their backoff expires, and failed or
+// skipped feeds contribute their cached articles if there are any. Feeds
+// marked dead are not fetched. An error
+// is only returned when no articles could be returned at all.
+func (c *Client) FetchMultipleFeeds(feeds []FeedInfo) ([]Article, []FetchResult, error) {
+	return c.FetchMultipleFeedsContext(context.Background(), feeds)
 }
 
-// FetchMultipleFeeds fetches multiple RSS feeds and returns all articles sorted by date
-func (c *Client) FetchMultipleFeeds(feeds []FeedInfo) ([]Article, error) {
-	var allArticles []Article
-	var errors []string
+// FetchMultipleFeedsContext is like FetchMultipleFeeds but stops fetching when
+// ctx is done. Feeds that were not fetched in time are reported as failed and
+// the context's error is returned.
+func (c *Client) FetchMultipleFeedsContext(ctx context.Context, feeds []FeedInfo) ([]Article, []FetchResult, error) {
+	articles := make([][]Article, len(feeds))
+	results := make([]FetchResult, len(feeds))
 
+	limit := make(chan struct{}, c.maxConcurrency)
+	hostLimits := make(map[string]chan struct{})
 	for _, feed := range feeds {
-		rss, err := c.FetchFeed(feed.URL)
-		if err != nil {
-			errors = append(errors, fmt.Sprintf("Failed to fetch %s: %v", feed.Name, err))
-			continue
+		host := feedHost(feed.URL)
+		if hostLimits[host] == nil {
+			hostLimits[host] = make(chan struct{}, c.maxPerHost)
 		}
+	}
+
+	var wg sync.WaitGroup
+	for i, feed := range feeds {
+		wg.Add(1)
+		go func(i int, feed FeedInfo) {
+			defer wg.Done()
 
-		for _, item := range rss.Channel.Items {
-			allArticles = append(allArticles, Article{
-				Title:       item.Title,
-				Link:        item.Link,
-				Description: item.Description,
-				PubDate:     parseTime(item.PubDate),
-				FeedName:    feed.Name,
-			})
+			if feed.Dead {
+				results[i] = FetchResult{Feed: feed, Status: FetchGone, Err: ErrFeedGone}
+				return
+			}
+
+			if failure, ok := c.failures.backoff(feed.URL, time.Now()); ok {
+				results[i] = FetchResult{
+					Feed:        feed,
+					Status:      FetchSkipped,
+					Err:         failure.lastErr,
+					Failures:    failure.count,
+					NextAttempt: failure.nextAttempt,
+				}
+				articles[i] = c.cachedArticles(feed)
+				results[i].ItemCount = len(articles[i])
+				return
+			}
+
+			// Wait for a host slot first so that feeds queued behind a busy
+			// host do not hold up the global limit
+			hostLimit := hostLimits[feedHost(feed.URL)]
+			if !acquire(ctx, hostLimit) {
+				results[i] = FetchResult{Feed: feed, Status: FetchFailed, Err: ctx.Err()}
+				return
+			}
+			defer func() { <-hostLimit }()
+			if !acquire(ctx, limit) {
+				results[i] = FetchResult{Feed: feed, Status: FetchFailed, Err: ctx.Err()}
+				return
+			}
+			defer func() { <-limit }()
+
+			results[i], articles[i] = c.fetchResult(ctx, feed)
+		}(i, feed)
+	}
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		// Partial results of an abandoned refresh are not worth showing
+		return nil, results, err
+	}
+
+	var allArticles []Article
+	var errors []string
+	undated := make(map[string]bool)
+	for i, result := range results {
+		// Feeds that are gone are reported through their status instead
+		if result.Err != nil && result.Status != FetchGone {
+			errors = append(errors, fmt.Sprintf("Failed to fetch %s: %v", result.Feed.Name, result.Err))
+		}
+		allArticles = append(allArticles, articles[i]...)
+		for _, article := range articles[i] {
+			if article.Undated {
+				undated[article.Key()] = true
+			}
 		}
 	}
+	c.cache.pruneFirstSeen(undated, time.Now().Add(-firstSeenRetention))
 
 	// Sort by publication date (newest first)
-	sort.Slice(allArticles, func(i, j int) bool {
+	sort.SliceStable(allArticles, func(i, j int) bool {
 		return allArticles[i].PubDate.After(allArticles[j].PubDate)
 	})
 
 	// If no articles were fetched but there were errors, return an error
 	if len(allArticles) == 0 && len(errors) > 0 {
-		return nil, fmt.Errorf("failed to fetch any feeds: %v", errors)
+		return nil, results, fmt.Errorf("failed to fetch any feeds: %v", errors)
 	}
 
-	return allArticles, nil
+	return allArticles, results, nil
 }
 
-// FeedInfo represents RSS feed configuration
-type FeedInfo struct {
-	Name string `json:"name"`
-	URL  string `json:"url"`
+// fetchResult fetches a single feed for FetchMultipleFeeds and reports the outcome
+func (c *Client) fetchResult(ctx context.Context, feed FeedInfo) (FetchResult, []Article) {
+	result := FetchResult{Feed: feed}
+	start := time.Now()
+	parsed, resp, err := c.fetch(ctx, feed, c.cache)
+	result.Duration = time.Since(start)
+
+	var httpErr *HTTPError
+	switch {
+	case resp != nil:
+		result.HTTPStatus = resp.statusCode
+	case errors.As(err, &httpErr):
+		result.HTTPStatus = httpErr.StatusCode
+	}
+
+	if errors.As(err, &httpErr) && httpErr.StatusCode == http.StatusGone {
+		// Not a failure worth retrying; the caller should stop polling
+		c.failures.record(feed.URL, nil, time.Now())
+		result.Status = FetchGone
+		result.Err = err
+		return result, nil
+	}
+
+	if ctx.Err() == nil {
+		failure := c.failures.record(feed.URL, err, time.Now())
+		result.Failures = failure.count
+		result.NextAttempt = failure.nextAttempt
+	}
+
+	if err != nil {
+		result.Status = FetchFailed
+		result.Err = err
+		// Keep showing what the feed had before it started failing
+		articles := c.cachedArticles(feed)
+		result.ItemCount = len(articles)
+		return result, articles
+	}
+	if resp.notModified {
+		result.Status = FetchNotModified
+	}
+	result.MovedTo = resp.movedTo
+
+	articles := c.feedArticles(feed, parsed)
+	result.ItemCount = len(articles)
+	return result, articles
 }
 
-// parseTime attempts to parse various date formats commonly used in RSS feeds
-func parseTime(pubDate string) time.Time {
-	layouts := []string{
-		time.RFC1123Z,
-		time.RFC1123,
-		"Mon, 2 Jan 2006 15:04:05 -0700",
-		"Mon, 2 Jan 2006 15:04:05 MST",
-		"2006-01-02T15:04:05Z07:00",
-		"2006-01-02T15:04:05Z",
+// cachedArticles returns the articles of a feed's cached copy, if any
+func (c *Client) cachedArticles(feed FeedInfo) []Article {
+	cached, ok := c.cache.Get(feed.URL)
+	if !ok {
+		return nil
 	}
+	return c.feedArticles(feed, cached.Feed)
+}
 
-	for _, layout := range layouts {
-		if t, err := time.Parse(layout, pubDate); err == nil {
-			return t
+// feedArticles converts the entries of a parsed feed to articles. Entries
+// without a usable date are dated when they were first seen, so that they
+// keep their place in the list across refreshes.
+func (c *Client) feedArticles(feed FeedInfo, parsed *Feed) []Article {
+	now := time.Now()
+	articles := make([]Article, 0, len(parsed.Entries))
+	for _, entry := range parsed.Entries {
+		article := newArticle(entry, feed)
+		if article.PubDate.IsZero() {
+			article.PubDate = c.cache.FirstSeen(article.Key(), now)
+			article.Undated = true
 		}
+		articles = append(articles, article)
+	}
+	return articles
+}
+
+// acquire takes a slot from a semaphore, giving up when ctx is done
+func acquire(ctx context.Context, semaphore chan struct{}) bool {
+	select {
+	case semaphore <- struct{}{}:
+		return true
+	case <-ctx.Done():
+		return false
 	}
-	return time.Now()
+}
+
+// feedHost returns the host a feed URL is fetched from, used to limit
+// concurrent requests per host
+func feedHost(rawURL string) string {
+	if u, err := url.Parse(rawURL); err == nil && u.Host != "" {
+		return strings.ToLower(u.Host)
+	}
+	return rawURL
+}
+
+// FeedInfo represents RSS feed configuration.
+// Header, cookie and credential values may reference environment variables
+// as ${NAME} so that secrets do not have to be stored in the feeds file.
+type FeedInfo struct {
+	Name      string            `json:"name"`
+	URL       string            `json:"url"`
+	Dead      bool              `json:"dead,omitempty"` // Set when the feed answered 410 Gone; dead feeds are not fetched
+	Headers   map[string]string `json:"headers,omitempty"`
+	UserAgent string            `json:"user_agent,omitempty"`
+	Auth      *FeedAuth         `json:"auth,omitempty"`
+	Cookies   map[string]string `json:"cookies,omitempty"`
+	Transport *TransportOptions `json:"transport,omitempty"` // Overrides the client's proxy and TLS settings
+	Filter    string            `json:"filter,omitempty"`    // Shell command the document is piped through before parsing
+	Scrape    *ScrapeOptions    `json:"scrape,omitempty"`    // Build the feed from an HTML page instead
 }
\ No newline at end of file
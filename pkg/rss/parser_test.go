@@ -1,10 +1,28 @@
 package rss
 
 import (
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
 	"testing"
+	"testing/iotest"
 	"time"
+
+	"golang.org/x/text/encoding/htmlindex"
 )
 
 func TestNewClient(t *testing.T) {
@@ -52,17 +70,17 @@ func TestFetchFeed(t *testing.T) {
 		t.Fatalf("FetchFeed returned error: %v", err)
 	}
 
-	if feed.Channel.Title != "Test Feed" {
-		t.Errorf("Expected title 'Test Feed', got '%s'", feed.Channel.Title)
+	if feed.Title != "Test Feed" {
+		t.Errorf("Expected title 'Test Feed', got '%s'", feed.Title)
 	}
 
-	if len(feed.Channel.Items) != 1 {
-		t.Errorf("Expected 1 item, got %d", len(feed.Channel.Items))
+	if len(feed.Entries) != 1 {
+		t.Errorf("Expected 1 item, got %d", len(feed.Entries))
 	}
 
-	item := feed.Channel.Items[0]
-	if item.Title != "Test Article" {
-		t.Errorf("Expected item title 'Test Article', got '%s'", item.Title)
+	entry := feed.Entries[0]
+	if entry.Title != "Test Article" {
+		t.Errorf("Expected entry title 'Test Article', got '%s'", entry.Title)
 	}
 }
 
@@ -131,7 +149,7 @@ func TestFetchMultipleFeeds(t *testing.T) {
 	}
 
 	client := NewClient(5 * time.Second)
-	articles, err := client.FetchMultipleFeeds(feeds)
+	articles, _, err := client.FetchMultipleFeeds(feeds)
 
 	if err != nil {
 		t.Fatalf("FetchMultipleFeeds returned error: %v", err)
@@ -148,20 +166,1900 @@ func TestFetchMultipleFeeds(t *testing.T) {
 }
 
 func TestParseTime(t *testing.T) {
+	utc := func(year int, month time.Month, day, hour, min, sec int) time.Time {
+		return time.Date(year, month, day, hour, min, sec, 0, time.UTC)
+	}
+	testCases := []struct {
+		input    string
+		expected time.Time
+	}{
+		{"Mon, 01 Jan 2024 12:00:00 GMT", utc(2024, 1, 1, 12, 0, 0)},
+		{"Mon, 01 Jan 2024 12:00:00 -0700", utc(2024, 1, 1, 19, 0, 0)},
+		{"Mon, 01 Jan 2024 12:00:00 UT", utc(2024, 1, 1, 12, 0, 0)},
+		{"Mon, 01 Jan 2024 12:00:00 EST", utc(2024, 1, 1, 17, 0, 0)},
+		{"Tue, 2 Jul 2024 08:30:00 PDT", utc(2024, 7, 2, 15, 30, 0)},
+		{"Sun, 01 Jan 2024 12:00:00 +0000", utc(2024, 1, 1, 12, 0, 0)}, // Wrong weekday
+		{"Mon, 01 Jan 2024 12:00 +0100", utc(2024, 1, 1, 11, 0, 0)},
+		{"Mon, 01 Jan 2024 12:00:00 +0000 (UTC)", utc(2024, 1, 1, 12, 0, 0)},
+		{"Monday, 01-Jan-24 12:00:00 GMT", utc(2024, 1, 1, 12, 0, 0)},
+		{"2 Jan 06", utc(2006, 1, 2, 0, 0, 0)},
+		{"02 January 2024", utc(2024, 1, 2, 0, 0, 0)},
+		{"January 2, 2024", utc(2024, 1, 2, 0, 0, 0)},
+		{"Jan 2, 2024 3:04 PM", utc(2024, 1, 2, 15, 4, 0)},
+		{"Mon Jan  2 15:04:05 2006", utc(2006, 1, 2, 15, 4, 5)},
+		{"2024-01-01T12:00:00Z", utc(2024, 1, 1, 12, 0, 0)},
+		{"2024-01-01T12:00:00.123456Z", utc(2024, 1, 1, 12, 0, 0).Add(123456 * time.Microsecond)},
+		{"2024-01-01T12:00:00+02:00", utc(2024, 1, 1, 10, 0, 0)},
+		{"2024-01-01T12:00:00+0200", utc(2024, 1, 1, 10, 0, 0)},
+		{"2024-01-01T12:00Z", utc(2024, 1, 1, 12, 0, 0)},
+		{"2024-01-01T12:00:00", utc(2024, 1, 1, 12, 0, 0)},
+		{"2024-01-01 12:00:00", utc(2024, 1, 1, 12, 0, 0)},
+		{"2024-01-01", utc(2024, 1, 1, 0, 0, 0)},
+		{"  2024-01-01  ", utc(2024, 1, 1, 0, 0, 0)},
+		{"invalid date", time.Time{}},
+		{"", time.Time{}},
+	}
+
+	for _, tc := range testCases {
+		result, ok := parseTime(tc.input)
+		if ok != !tc.expected.IsZero() || !result.Equal(tc.expected) {
+			t.Errorf("parseTime(%q) = %v, %v; want %v", tc.input, result, ok, tc.expected)
+		}
+	}
+}
+
+func TestUndatedArticles(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<rss version="2.0"><channel><title>Feed</title>
+<item><title>Dated</title><link>https://example.com/dated</link><pubDate>Mon, 01 Jan 2024 12:00:00 GMT</pubDate></item>
+<item><title>Undated</title><link>https://example.com/undated</link><pubDate>sometime last week</pubDate></item>
+</channel></rss>`))
+	}))
+	defer server.Close()
+
+	feed, err := ParseFeed("", []byte(`<rss version="2.0"><channel><item><title>A</title><pubDate>soon</pubDate></item></channel></rss>`))
+	if err != nil || !feed.Entries[0].PubDate.IsZero() {
+		t.Errorf("Expected an unparsable date to be left zero, got %v (%v)", feed.Entries[0].PubDate, err)
+	}
+
+	feeds := []FeedInfo{{Name: "Feed", URL: server.URL}}
+	cache := NewCache(nil)
+	client := NewClient(5 * time.Second)
+	client.SetCache(cache)
+
+	fetch := func() map[string]Article {
+		articles, _, err := client.FetchMultipleFeeds(feeds)
+		if err != nil {
+			t.Fatalf("FetchMultipleFeeds returned error: %v", err)
+		}
+		byTitle := make(map[string]Article)
+		for _, article := range articles {
+			byTitle[article.Title] = article
+		}
+		return byTitle
+	}
+
+	first := fetch()
+	if dated := first["Dated"]; dated.Undated || !dated.PubDate.Equal(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)) {
+		t.Errorf("Expected the dated article to keep its date, got %+v", dated)
+	}
+	undated := first["Undated"]
+	if !undated.Undated || undated.PubDate.IsZero() {
+		t.Fatalf("Expected the undated article to be dated when first seen, got %+v", undated)
+	}
+
+	time.Sleep(10 * time.Millisecond)
+	if again := fetch()["Undated"]; !again.PubDate.Equal(undated.PubDate) {
+		t.Errorf("Expected the first-seen date to be stable, got %v then %v", undated.PubDate, again.PubDate)
+	}
+	if len(cache.FirstSeenTimes()) != 1 {
+		t.Errorf("Expected one first-seen time, got %v", cache.FirstSeenTimes())
+	}
+
+	// Old first-seen times are forgotten once their articles disappear
+	cache.pruneFirstSeen(nil, time.Now().Add(time.Hour))
+	if len(cache.FirstSeenTimes()) != 0 {
+		t.Errorf("Expected first-seen times to be pruned, got %v", cache.FirstSeenTimes())
+	}
+}
+
+func TestFetchAtomFeed(t *testing.T) {
+	testAtom := `<?xml version="1.0" encoding="UTF-8"?>
+<feed xmlns="http://www.w3.org/2005/Atom">
+	<title>Atom Feed</title>
+	<subtitle>A test Atom feed</subtitle>
+	<link rel="self" href="https://example.com/feed.atom"/>
+	<link href="https://example.com"/>
+	<entry>
+		<title>Atom Entry</title>
+		<link rel="edit" href="https://example.com/edit/1"/>
+		<link rel="alternate" type="text/html" href="https://example.com/entry1"/>
+		<updated>2024-01-02T12:00:00Z</updated>
+		<published>2024-01-01T12:00:00Z</published>
+		<summary>Entry summary</summary>
+		<content type="html">&lt;p&gt;Full content&lt;/p&gt;</content>
+		<author><name>Jane Doe</name></author>
+		<author><name>John Doe</name></author>
+	</entry>
+	<entry>
+		<title>Updated Only</title>
+		<link href="https://example.com/entry2"/>
+		<updated>2024-01-03T08:30:00.123Z</updated>
+		<content type="xhtml"><div xmlns="http://www.w3.org/1999/xhtml"><p>Inline</p></div></content>
+	</entry>
+</feed>`
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/atom+xml")
+		w.Write([]byte(testAtom))
+	}))
+	defer server.Close()
+
+	client := NewClient(5 * time.Second)
+	feed, err := client.FetchFeed(server.URL)
+	if err != nil {
+		t.Fatalf("FetchFeed returned error: %v", err)
+	}
+
+	if feed.Title != "Atom Feed" {
+		t.Errorf("Expected title 'Atom Feed', got '%s'", feed.Title)
+	}
+	if feed.Link != "https://example.com" {
+		t.Errorf("Expected link 'https://example.com', got '%s'", feed.Link)
+	}
+	if len(feed.Entries) != 2 {
+		t.Fatalf("Expected 2 entries, got %d", len(feed.Entries))
+	}
+
+	entry := feed.Entries[0]
+	if entry.Link != "https://example.com/entry1" {
+		t.Errorf("Expected alternate link, got '%s'", entry.Link)
+	}
+	if !entry.PubDate.Equal(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)) {
+		t.Errorf("Expected published date, got %v", entry.PubDate)
+	}
+	if entry.Description != "Entry summary" {
+		t.Errorf("Expected summary as description, got '%s'", entry.Description)
+	}
+	if entry.Author != "Jane Doe, John Doe" {
+		t.Errorf("Expected both authors, got '%s'", entry.Author)
+	}
+
+	entry = feed.Entries[1]
+	if !entry.PubDate.Equal(time.Date(2024, 1, 3, 8, 30, 0, 123000000, time.UTC)) {
+		t.Errorf("Expected updated date as fallback, got %v", entry.PubDate)
+	}
+	if !strings.Contains(entry.Description, "<p>Inline</p>") {
+		t.Errorf("Expected XHTML content as description, got '%s'", entry.Description)
+	}
+
+	articles, _, err := client.FetchMultipleFeeds([]FeedInfo{{Name: "Atom", URL: server.URL}})
+	if err != nil {
+		t.Fatalf("FetchMultipleFeeds returned error: %v", err)
+	}
+	if len(articles) != 2 {
+		t.Fatalf("Expected 2 articles, got %d", len(articles))
+	}
+	if articles[0].Title != "Updated Only" {
+		t.Errorf("Expected newest entry first, got '%s'", articles[0].Title)
+	}
+	if articles[1].Author != "Jane Doe, John Doe" {
+		t.Errorf("Expected author on article, got '%s'", articles[1].Author)
+	}
+}
+
+func TestFetchRDFFeed(t *testing.T) {
+	testRDF := `<?xml version="1.0" encoding="UTF-8"?>
+<rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#"
+	xmlns="http://purl.org/rss/1.0/"
+	xmlns:dc="http://purl.org/dc/elements/1.1/">
+	<channel rdf:about="https://example.com/">
+		<title>RDF Feed</title>
+		<link>https://example.com/</link>
+		<description>A test RDF feed</description>
+		<items>
+			<rdf:Seq>
+				<rdf:li rdf:resource="https://example.com/story1"/>
+				<rdf:li rdf:resource="https://example.com/story2"/>
+			</rdf:Seq>
+		</items>
+	</channel>
+	<item rdf:about="https://example.com/story1">
+		<title>Story 1</title>
+		<link>https://example.com/story1</link>
+		<description>First story</description>
+		<dc:date>2024-01-01T12:00:00+00:00</dc:date>
+		<dc:creator>timothy</dc:creator>
+	</item>
+	<item rdf:about="https://example.com/story2">
+		<title>Story 2</title>
+		<link>https://example.com/story2</link>
+		<description>Second story</description>
+		<dc:date>2024-01-02T09:30+01:00</dc:date>
+	</item>
+</rdf:RDF>`
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/rdf+xml")
+		w.Write([]byte(testRDF))
+	}))
+	defer server.Close()
+
+	client := NewClient(5 * time.Second)
+	feed, err := client.FetchFeed(server.URL)
+	if err != nil {
+		t.Fatalf("FetchFeed returned error: %v", err)
+	}
+
+	if feed.Title != "RDF Feed" {
+		t.Errorf("Expected title 'RDF Feed', got '%s'", feed.Title)
+	}
+	if len(feed.Entries) != 2 {
+		t.Fatalf("Expected 2 entries, got %d", len(feed.Entries))
+	}
+	if feed.Entries[0].Author != "timothy" {
+		t.Errorf("Expected dc:creator as author, got '%s'", feed.Entries[0].Author)
+	}
+
+	articles, _, err := client.FetchMultipleFeeds([]FeedInfo{{Name: "RDF", URL: server.URL}})
+	if err != nil {
+		t.Fatalf("FetchMultipleFeeds returned error: %v", err)
+	}
+	if len(articles) != 2 {
+		t.Fatalf("Expected 2 articles, got %d", len(articles))
+	}
+
+	expected := time.Date(2024, 1, 2, 8, 30, 0, 0, time.UTC)
+	if !articles[0].PubDate.Equal(expected) {
+		t.Errorf("Expected dc:date %v, got %v", expected, articles[0].PubDate)
+	}
+}
+
+func TestFetchJSONFeed(t *testing.T) {
+	testJSON := `{
+	"version": "https://jsonfeed.org/version/1.1",
+	"title": "JSON Feed",
+	"home_page_url": "https://example.com/",
+	"authors": [{"name": "Feed Author"}],
+	"items": [
+		{
+			"id": "1",
+			"url": "https://example.com/1",
+			"title": "HTML Item",
+			"content_html": "<p>Hello</p>",
+			"date_published": "2024-01-01T12:00:00Z",
+			"authors": [{"name": "Item Author"}],
+			"tags": ["go", "feeds"],
+			"attachments": [{"url": "https://example.com/1.mp3", "mime_type": "audio/mpeg", "size_in_bytes": 1234}]
+		},
+		{
+			"id": "2",
+			"url": "https://example.com/2",
+			"title": "Text Item",
+			"content_text": "Plain text",
+			"date_published": "2024-01-02T12:00:00Z"
+		}
+	]
+}`
+
+	testCases := []struct {
+		name        string
+		contentType string
+	}{
+		{"content type", "application/feed+json; charset=utf-8"},
+		{"sniffed", "text/plain"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", tc.contentType)
+				w.Write([]byte(testJSON))
+			}))
+			defer server.Close()
+
+			client := NewClient(5 * time.Second)
+			feed, err := client.FetchFeed(server.URL)
+			if err != nil {
+				t.Fatalf("FetchFeed returned error: %v", err)
+			}
+			if feed.Title != "JSON Feed" {
+				t.Errorf("Expected title 'JSON Feed', got '%s'", feed.Title)
+			}
+
+			articles, _, err := client.FetchMultipleFeeds([]FeedInfo{{Name: "JSON", URL: server.URL}})
+			if err != nil {
+				t.Fatalf("FetchMultipleFeeds returned error: %v", err)
+			}
+			if len(articles) != 2 {
+				t.Fatalf("Expected 2 articles, got %d", len(articles))
+			}
+
+			text, html := articles[0], articles[1]
+			if text.Description != "Plain text" {
+				t.Errorf("Expected content_text as description, got '%s'", text.Description)
+			}
+			if text.Author != "Feed Author" {
+				t.Errorf("Expected feed author as fallback, got '%s'", text.Author)
+			}
+			if html.Description != "<p>Hello</p>" {
+				t.Errorf("Expected content_html as description, got '%s'", html.Description)
+			}
+			if html.Author != "Item Author" {
+				t.Errorf("Expected item author, got '%s'", html.Author)
+			}
+			if len(html.Categories) != 2 || html.Categories[0] != "go" {
+				t.Errorf("Expected tags as categories, got %v", html.Categories)
+			}
+			if len(html.Enclosures) != 1 || html.Enclosures[0].Type != "audio/mpeg" || html.Enclosures[0].Length != 1234 {
+				t.Errorf("Expected attachment as enclosure, got %+v", html.Enclosures)
+			}
+		})
+	}
+}
+
+func TestParseFeedDetectsFormat(t *testing.T) {
+	testCases := []struct {
+		name        string
+		contentType string
+		body        string
+		format      string
+	}{
+		{"rss", "application/rss+xml", `<rss version="2.0"><channel><title>T</title></channel></rss>`, "rss"},
+		{"atom", "application/atom+xml", `<feed xmlns="http://www.w3.org/2005/Atom"><title>T</title></feed>`, "atom"},
+		{"rdf", "application/rdf+xml", `<rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#" xmlns="http://purl.org/rss/1.0/"><channel><title>T</title></channel></rdf:RDF>`, "rdf"},
+		{"json", "application/feed+json", `{"version": "https://jsonfeed.org/version/1.1", "title": "T"}`, "json"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			feed, err := ParseFeed(tc.contentType, []byte(tc.body))
+			if err != nil {
+				t.Fatalf("ParseFeed returned error: %v", err)
+			}
+			if feed.Format != tc.format {
+				t.Errorf("Expected format '%s', got '%s'", tc.format, feed.Format)
+			}
+			if feed.Title != "T" {
+				t.Errorf("Expected title 'T', got '%s'", feed.Title)
+			}
+		})
+	}
+
+	if _, err := ParseFeed("text/html", []byte("<html><body>Not a feed</body></html>")); !errors.Is(err, ErrUnknownFormat) {
+		t.Errorf("Expected ErrUnknownFormat for HTML, got %v", err)
+	}
+	if _, err := ParseFeed("application/json", []byte(`{"error": "rate limited"}`)); !errors.Is(err, ErrUnknownFormat) {
+		t.Errorf("Expected ErrUnknownFormat for JSON that is not a JSON Feed, got %v", err)
+	}
+}
+
+func TestRegisterFormat(t *testing.T) {
+	RegisterFormat("test-lines", func(contentType string, body []byte) bool {
+		return contentType == "text/x-test-lines"
+	}, func(body []byte) (*Feed, error) {
+		feed := &Feed{Title: "Lines"}
+		for _, line := range strings.Split(strings.TrimSpace(string(body)), "\n") {
+			feed.Entries = append(feed.Entries, Entry{Title: line})
+		}
+		return feed, nil
+	})
+
+	feed, err := ParseFeed("text/x-test-lines", []byte("one\ntwo\n"))
+	if err != nil {
+		t.Fatalf("ParseFeed returned error: %v", err)
+	}
+	if feed.Format != "test-lines" {
+		t.Errorf("Expected format 'test-lines', got '%s'", feed.Format)
+	}
+	if len(feed.Entries) != 2 || feed.Entries[1].Title != "two" {
+		t.Errorf("Expected entries from custom parser, got %+v", feed.Entries)
+	}
+
+	found := false
+	for _, name := range Formats() {
+		if name == "test-lines" {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("Expected 'test-lines' in registered formats %v", Formats())
+	}
+}
+
+func TestFetchFeedNamespaces(t *testing.T) {
+	testRSS := `<?xml version="1.0" encoding="UTF-8"?>
+<rss version="2.0"
+	xmlns:content="http://purl.org/rss/1.0/modules/content/"
+	xmlns:dc="http://purl.org/dc/elements/1.1/"
+	xmlns:slash="http://purl.org/rss/1.0/modules/slash/">
+	<channel>
+		<title>Namespaced Feed</title>
+		<item>
+			<title>Full Article</title>
+			<link>https://example.com/full</link>
+			<description>Teaser</description>
+			<content:encoded><![CDATA[<p>The whole story</p>]]></content:encoded>
+			<dc:creator>Jane Doe</dc:creator>
+			<author>jane@example.com (Jane Doe)</author>
+			<category>Go</category>
+			<category domain="https://example.com/tags">Feeds</category>
+			<comments>https://example.com/full#comments</comments>
+			<slash:comments>42</slash:comments>
+			<pubDate>Mon, 01 Jan 2024 12:00:00 GMT</pubDate>
+		</item>
+		<item>
+			<title>Author Only</title>
+			<author>john@example.com</author>
+		</item>
+	</channel>
+</rss>`
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(testRSS))
+	}))
+	defer server.Close()
+
+	client := NewClient(5 * time.Second)
+	articles, _, err := client.FetchMultipleFeeds([]FeedInfo{{Name: "NS", URL: server.URL}})
+	if err != nil {
+		t.Fatalf("FetchMultipleFeeds returned error: %v", err)
+	}
+	if len(articles) != 2 {
+		t.Fatalf("Expected 2 articles, got %d", len(articles))
+	}
+
+	var full, authorOnly Article
+	for _, article := range articles {
+		if article.Title == "Full Article" {
+			full = article
+		} else {
+			authorOnly = article
+		}
+	}
+
+	if full.Content != "<p>The whole story</p>" {
+		t.Errorf("Expected content:encoded as content, got '%s'", full.Content)
+	}
+	if full.Description != "Teaser" {
+		t.Errorf("Expected description to be kept, got '%s'", full.Description)
+	}
+	if full.Author != "Jane Doe" {
+		t.Errorf("Expected dc:creator as author, got '%s'", full.Author)
+	}
+	if len(full.Categories) != 2 || full.Categories[1] != "Feeds" {
+		t.Errorf("Expected 2 categories, got %v", full.Categories)
+	}
+	if full.CommentsURL != "https://example.com/full#comments" {
+		t.Errorf("Expected comments URL, got '%s'", full.CommentsURL)
+	}
+	if full.CommentCount != 42 {
+		t.Errorf("Expected 42 comments, got %d", full.CommentCount)
+	}
+	if authorOnly.Author != "john@example.com" {
+		t.Errorf("Expected author element as fallback, got '%s'", authorOnly.Author)
+	}
+}
+
+func TestEntryIDs(t *testing.T) {
+	testRSS := `<rss version="2.0"><channel><title>IDs</title>
+		<item><title>With GUID</title><link>https://example.com/a?utm=1</link><guid isPermaLink="false">tag:example.com,2024:1</guid></item>
+		<item><title>Permalink GUID</title><guid>https://example.com/b</guid></item>
+		<item><title>Link Only</title><link>https://example.com/c</link></item>
+		<item><title>Nothing</title><pubDate>Mon, 01 Jan 2024 12:00:00 GMT</pubDate></item>
+		<item><title>Nothing</title><pubDate>Tue, 02 Jan 2024 12:00:00 GMT</pubDate></item>
+	</channel></rss>`
+
+	feed, err := ParseFeed("application/rss+xml", []byte(testRSS))
+	if err != nil {
+		t.Fatalf("ParseFeed returned error: %v", err)
+	}
+
+	if id := feed.Entries[0].ID; id != "tag:example.com,2024:1" {
+		t.Errorf("Expected GUID as ID, got '%s'", id)
+	}
+	if entry := feed.Entries[1]; entry.ID != "https://example.com/b" || entry.Link != "https://example.com/b" {
+		t.Errorf("Expected permalink GUID as ID and link, got '%s' and '%s'", entry.ID, entry.Link)
+	}
+	if id := feed.Entries[2].ID; id != "https://example.com/c" {
+		t.Errorf("Expected link as ID, got '%s'", id)
+	}
+
+	hashed1, hashed2 := feed.Entries[3].ID, feed.Entries[4].ID
+	if !strings.HasPrefix(hashed1, "sha1:") {
+		t.Errorf("Expected hashed ID, got '%s'", hashed1)
+	}
+	if hashed1 == hashed2 {
+		t.Error("Expected entries with different dates to have different IDs")
+	}
+
+	again, _ := ParseFeed("application/rss+xml", []byte(testRSS))
+	if again.Entries[3].ID != hashed1 {
+		t.Error("Expected hashed ID to be stable across parses")
+	}
+
+	testAtom := `<feed xmlns="http://www.w3.org/2005/Atom"><entry><id>urn:uuid:1225c695</id><link href="https://example.com/d"/></entry></feed>`
+	feed, err = ParseFeed("application/atom+xml", []byte(testAtom))
+	if err != nil {
+		t.Fatalf("ParseFeed returned error: %v", err)
+	}
+	if id := feed.Entries[0].ID; id != "urn:uuid:1225c695" {
+		t.Errorf("Expected Atom id as ID, got '%s'", id)
+	}
+}
+
+func TestParsePodcastFeed(t *testing.T) {
+	testRSS := `<rss version="2.0"
+	xmlns:itunes="http://www.itunes.com/dtds/podcast-1.0.dtd"
+	xmlns:media="http://search.yahoo.com/mrss/">
+	<channel>
+		<title>Podcast</title>
+		<itunes:image href="https://example.com/show.jpg"/>
+		<item>
+			<title>Episode 12</title>
+			<enclosure url="https://example.com/ep12.mp3" length="12345678" type="audio/mpeg"/>
+			<media:content url="https://example.com/ep12.mp3" type="audio/mpeg"/>
+			<media:content url="https://example.com/ep12.ogg" type="audio/ogg" fileSize="999"/>
+			<itunes:duration>1:02:03</itunes:duration>
+			<itunes:episode>12</itunes:episode>
+			<itunes:image href="https://example.com/ep12.jpg"/>
+		</item>
+		<item>
+			<title>Video</title>
+			<media:group>
+				<media:content url="https://example.com/video.mp4" type="video/mp4" duration="90"/>
+			</media:group>
+		</item>
+	</channel>
+</rss>`
+
+	feed, err := ParseFeed("application/rss+xml", []byte(testRSS))
+	if err != nil {
+		t.Fatalf("ParseFeed returned error: %v", err)
+	}
+
+	episode := feed.Entries[0]
+	if len(episode.Enclosures) != 2 {
+		t.Fatalf("Expected 2 enclosures without duplicates, got %+v", episode.Enclosures)
+	}
+	if episode.Enclosures[0].Length != 12345678 || episode.Enclosures[1].Type != "audio/ogg" {
+		t.Errorf("Unexpected enclosures %+v", episode.Enclosures)
+	}
+	if episode.Duration != time.Hour+2*time.Minute+3*time.Second {
+		t.Errorf("Expected duration 1:02:03, got %v", episode.Duration)
+	}
+	if episode.Episode != 12 {
+		t.Errorf("Expected episode 12, got %d", episode.Episode)
+	}
+	if episode.Image != "https://example.com/ep12.jpg" {
+		t.Errorf("Expected episode image, got '%s'", episode.Image)
+	}
+
+	video := feed.Entries[1]
+	if len(video.Enclosures) != 1 || video.Enclosures[0].URL != "https://example.com/video.mp4" {
+		t.Errorf("Expected media:group content as enclosure, got %+v", video.Enclosures)
+	}
+	if video.Duration != 90*time.Second {
+		t.Errorf("Expected media duration of 90s, got %v", video.Duration)
+	}
+	if video.Image != "https://example.com/show.jpg" {
+		t.Errorf("Expected channel image as fallback, got '%s'", video.Image)
+	}
+}
+
+func TestParseInvalidNumbers(t *testing.T) {
+	testRSS := `<rss version="2.0"
+	xmlns:slash="http://purl.org/rss/1.0/modules/slash/"
+	xmlns:itunes="http://www.itunes.com/dtds/podcast-1.0.dtd"
+	xmlns:media="http://search.yahoo.com/mrss/">
+	<channel>
+		<title>Podcast</title>
+		<item>
+			<title>Unknown length</title>
+			<enclosure url="https://example.com/a.mp3" length="unknown" type="audio/mpeg"/>
+			<media:content url="https://example.com/a.ogg" fileSize="big" duration="12.5"/>
+			<slash:comments> </slash:comments>
+			<itunes:episode>Bonus</itunes:episode>
+		</item>
+		<item>
+			<title>Valid</title>
+			<enclosure url="https://example.com/b.mp3" length=" 42 " type="audio/mpeg"/>
+			<slash:comments> 7 </slash:comments>
+		</item>
+	</channel>
+</rss>`
+
+	feed, err := ParseFeed("application/rss+xml", []byte(testRSS))
+	if err != nil {
+		t.Fatalf("ParseFeed returned error: %v", err)
+	}
+	if len(feed.Entries) != 2 || feed.Entries[0].Enclosures[0].Length != 0 || feed.Entries[1].Enclosures[0].Length != 42 {
+		t.Errorf("Expected invalid lengths to be ignored, got %+v", feed.Entries)
+	}
+	if feed.Entries[0].CommentCount != 0 || feed.Entries[1].CommentCount != 7 {
+		t.Errorf("Expected invalid comment counts to be ignored, got %d and %d", feed.Entries[0].CommentCount, feed.Entries[1].CommentCount)
+	}
+	if bonus := feed.Entries[0]; bonus.Episode != 0 || bonus.Duration != 12500*time.Millisecond || len(bonus.Enclosures) != 2 || bonus.Enclosures[1].Length != 0 {
+		t.Errorf("Expected invalid podcast metadata to be ignored, got %+v", bonus)
+	}
+
+	testAtom := `<feed xmlns="http://www.w3.org/2005/Atom"><title>T</title>
+		<entry><title>A</title><link rel="enclosure" href="https://example.com/a.mp3" length="n/a"/></entry>
+	</feed>`
+	feed, err = ParseFeed("application/atom+xml", []byte(testAtom))
+	if err != nil {
+		t.Fatalf("ParseFeed returned error: %v", err)
+	}
+	if len(feed.Entries[0].Enclosures) != 1 || feed.Entries[0].Enclosures[0].Length != 0 {
+		t.Errorf("Expected an Atom enclosure without a valid length, got %+v", feed.Entries[0].Enclosures)
+	}
+
+	testJSON := `{"version": "https://jsonfeed.org/version/1.1", "items": [
+		{"id": "1", "attachments": [{"url": "https://example.com/c.mp3", "duration_in_seconds": 123.5}]}
+	]}`
+	feed, err = ParseFeed("application/feed+json", []byte(testJSON))
+	if err != nil {
+		t.Fatalf("ParseFeed returned error: %v", err)
+	}
+	if feed.Entries[0].Duration != 123*time.Second+500*time.Millisecond {
+		t.Errorf("Expected a fractional duration of 123.5s, got %v", feed.Entries[0].Duration)
+	}
+}
+
+func TestParseDuration(t *testing.T) {
 	testCases := []struct {
 		input    string
-		expected bool // whether parsing should succeed
+		expected time.Duration
+	}{
+		{"3600", time.Hour},
+		{"45:30", 45*time.Minute + 30*time.Second},
+		{"01:00:05", time.Hour + 5*time.Second},
+		{"", 0},
+		{"unknown", 0},
+	}
+
+	for _, tc := range testCases {
+		if result := parseDuration(tc.input); result != tc.expected {
+			t.Errorf("parseDuration(%q) = %v, expected %v", tc.input, result, tc.expected)
+		}
+	}
+}
+
+func TestFetchFeedCharsets(t *testing.T) {
+	encode := func(t *testing.T, label, s string) []byte {
+		enc, err := htmlindex.Get(label)
+		if err != nil {
+			t.Fatalf("Unknown charset %s: %v", label, err)
+		}
+		b, err := enc.NewEncoder().Bytes([]byte(s))
+		if err != nil {
+			t.Fatalf("Failed to encode test feed: %v", err)
+		}
+		return b
+	}
+
+	feedWith := func(decl, title string) string {
+		return decl + `<rss version="2.0"><channel><title>` + title + `</title>
+			<item><title>` + title + `</title><link>https://example.com/1</link></item>
+		</channel></rss>`
+	}
+
+	testCases := []struct {
+		name        string
+		contentType string
+		body        []byte
+		title       string
 	}{
-		{"Mon, 01 Jan 2024 12:00:00 GMT", true},
-		{"Mon, 01 Jan 2024 12:00:00 -0700", true},
-		{"2024-01-01T12:00:00Z", true},
-		{"invalid date", false}, // This will return time.Now(), so we can't test exact value
+		{
+			name:        "ISO-8859-1 declaration",
+			contentType: "application/rss+xml",
+			body:        encode(t, "iso-8859-1", feedWith(`<?xml version="1.0" encoding="ISO-8859-1"?>`, "Café Müller")),
+			title:       "Café Müller",
+		},
+		{
+			name:        "windows-1252 header",
+			contentType: "text/xml; charset=windows-1252",
+			body:        encode(t, "windows-1252", feedWith(`<?xml version="1.0"?>`, "“Smart” quotes €")),
+			title:       "“Smart” quotes €",
+		},
+		{
+			name:        "header overrides declaration",
+			contentType: "application/xml; charset=Shift_JIS",
+			body:        encode(t, "shift_jis", feedWith(`<?xml version="1.0" encoding="Shift_JIS"?>`, "日本語のニュース")),
+			title:       "日本語のニュース",
+		},
+		{
+			name:        "Shift_JIS declaration",
+			contentType: "application/xml",
+			body:        encode(t, "shift_jis", feedWith(`<?xml version='1.0' encoding='Shift_JIS'?>`, "日本語のニュース")),
+			title:       "日本語のニュース",
+		},
+		{
+			name:        "UTF-8 header overrides declaration",
+			contentType: "application/rss+xml; charset=utf-8",
+			body:        []byte(feedWith(`<?xml version="1.0" encoding="ISO-8859-1"?>`, "Café")),
+			title:       "Café",
+		},
+		{
+			name:        "UTF-8 byte order mark overrides declaration",
+			contentType: "application/rss+xml",
+			body:        append([]byte{0xEF, 0xBB, 0xBF}, feedWith(`<?xml version="1.0" encoding="ISO-8859-1"?>`, "Café")...),
+			title:       "Café",
+		},
 	}
 
 	for _, tc := range testCases {
-		result := parseTime(tc.input)
-		if tc.expected && result.IsZero() {
-			t.Errorf("parseTime(%s) returned zero time, expected valid time", tc.input)
+		t.Run(tc.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", tc.contentType)
+				w.Write(tc.body)
+			}))
+			defer server.Close()
+
+			client := NewClient(5 * time.Second)
+			feed, err := client.FetchFeed(server.URL)
+			if err != nil {
+				t.Fatalf("FetchFeed returned error: %v", err)
+			}
+			if feed.Title != tc.title {
+				t.Errorf("Expected title %q, got %q", tc.title, feed.Title)
+			}
+			if len(feed.Entries) != 1 || feed.Entries[0].Title != tc.title {
+				t.Errorf("Expected entry title %q, got %+v", tc.title, feed.Entries)
+			}
+
+			parsed, err := ParseFeed(tc.contentType, tc.body)
+			if err != nil || parsed.Title != tc.title {
+				t.Errorf("ParseFeed: expected title %q, got %+v (%v)", tc.title, parsed, err)
+			}
+		})
+	}
+
+	_, err := ParseFeed("application/xml", []byte(`<?xml version="1.0" encoding="x-unknown"?><rss></rss>`))
+	if err == nil || !strings.Contains(err.Error(), "unsupported charset") {
+		t.Errorf("Expected unsupported charset error, got %v", err)
+	}
+}
+
+func TestDiscover(t *testing.T) {
+	feedXML := `<rss version="2.0"><channel><title>Site Feed</title></channel></rss>`
+	withLinks := `<!DOCTYPE html>
+<html><head>
+	<title>Blog</title>
+	<link rel="stylesheet" href="/style.css">
+	<link rel="alternate" type="application/rss+xml" title="Posts" href="/posts.xml">
+	<link rel="alternate" type="application/atom+xml" title="Comments" href="https://other.example.com/comments.atom">
+	<link rel="alternate" type="application/feed+json" href="feed.json">
+	<link rel="alternate" hreflang="de" href="/de/">
+</head><body><link rel="alternate" type="application/rss+xml" href="/ignored.xml"></body></html>`
+	withoutLinks := `<html><head><title>Plain</title></head><body>Hello</body></html>`
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/blog/", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write([]byte(withLinks))
+	})
+	mux.HandleFunc("/plain", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(withoutLinks))
+	})
+	mux.HandleFunc("/rss.xml", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/rss+xml")
+		w.Write([]byte(feedXML))
+	})
+	mux.HandleFunc("/served-as-html", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(feedXML))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	client := NewClient(5 * time.Second)
+
+	if _, err := client.FetchFeed(server.URL + "/blog/"); !errors.Is(err, ErrHTMLPage) {
+		t.Errorf("Expected ErrHTMLPage for an HTML page, got %v", err)
+	}
+
+	feeds, err := client.Discover(server.URL + "/blog/")
+	if err != nil {
+		t.Fatalf("Discover returned error: %v", err)
+	}
+	expected := []DiscoveredFeed{
+		{Title: "Posts", URL: server.URL + "/posts.xml", Type: "application/rss+xml"},
+		{Title: "Comments", URL: "https://other.example.com/comments.atom", Type: "application/atom+xml"},
+		{Title: "", URL: server.URL + "/blog/feed.json", Type: "application/feed+json"},
+	}
+	if len(feeds) != len(expected) {
+		t.Fatalf("Expected %d feeds, got %+v", len(expected), feeds)
+	}
+	for i := range expected {
+		if feeds[i] != expected[i] {
+			t.Errorf("Feed %d: expected %+v, got %+v", i, expected[i], feeds[i])
 		}
 	}
-}
\ No newline at end of file
+
+	// Pages without feed links fall back to common paths
+	feeds, err = client.Discover(server.URL + "/plain")
+	if err != nil {
+		t.Fatalf("Discover returned error: %v", err)
+	}
+	if len(feeds) != 1 || feeds[0].URL != server.URL+"/rss.xml" || feeds[0].Title != "Site Feed" {
+		t.Errorf("Expected /rss.xml to be found, got %+v", feeds)
+	}
+
+	// Feed URLs are returned as is
+	feeds, err = client.Discover(server.URL + "/rss.xml")
+	if err != nil {
+		t.Fatalf("Discover returned error: %v", err)
+	}
+	if len(feeds) != 1 || feeds[0].URL != server.URL+"/rss.xml" || feeds[0].Type != "rss" {
+		t.Errorf("Expected the feed itself, got %+v", feeds)
+	}
+
+	// Even when served as HTML
+	feeds, err = client.Discover(server.URL + "/served-as-html")
+	if err != nil {
+		t.Fatalf("Discover returned error: %v", err)
+	}
+	if len(feeds) != 1 || feeds[0].URL != server.URL+"/served-as-html" || feeds[0].Type != "rss" {
+		t.Errorf("Expected the feed served as HTML itself, got %+v", feeds)
+	}
+}
+
+func TestFetchFeedConditional(t *testing.T) {
+	rssXML := `<rss version="2.0"><channel><title>Cached</title>
+<item><title>First</title><guid>1</guid></item>
+</channel></rss>`
+
+	var requests, fullResponses int
+	var lastIfNoneMatch, lastIfModifiedSince string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		lastIfNoneMatch = r.Header.Get("If-None-Match")
+		lastIfModifiedSince = r.Header.Get("If-Modified-Since")
+		if lastIfNoneMatch == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		fullResponses++
+		w.Header().Set("ETag", `"v1"`)
+		w.Header().Set("Last-Modified", "Mon, 02 Jan 2006 15:04:05 GMT")
+		w.Write([]byte(rssXML))
+	}))
+	defer server.Close()
+
+	client := NewClient(5 * time.Second)
+
+	// Without a cache every request downloads the feed
+	if _, err := client.FetchFeed(server.URL); err != nil {
+		t.Fatalf("FetchFeed returned error: %v", err)
+	}
+	if lastIfNoneMatch != "" {
+		t.Errorf("Expected unconditional request without a cache, got If-None-Match %q", lastIfNoneMatch)
+	}
+
+	cache := NewCache(nil)
+	client.SetCache(cache)
+	if _, err := client.FetchFeed(server.URL); err != nil {
+		t.Fatalf("FetchFeed returned error: %v", err)
+	}
+	entry, ok := cache.Get(server.URL)
+	if !ok || entry.ETag != `"v1"` || entry.LastModified != "Mon, 02 Jan 2006 15:04:05 GMT" {
+		t.Fatalf("Expected validators to be cached, got %+v", entry)
+	}
+
+	feed, err := client.FetchFeed(server.URL)
+	if err != nil {
+		t.Fatalf("FetchFeed returned error on 304: %v", err)
+	}
+	if lastIfNoneMatch != `"v1"` || lastIfModifiedSince != "Mon, 02 Jan 2006 15:04:05 GMT" {
+		t.Errorf("Expected conditional headers, got If-None-Match %q and If-Modified-Since %q", lastIfNoneMatch, lastIfModifiedSince)
+	}
+	if feed.Title != "Cached" || len(feed.Entries) != 1 || feed.Entries[0].ID != "1" {
+		t.Errorf("Expected cached feed on 304, got %+v", feed)
+	}
+	if requests != 3 || fullResponses != 2 {
+		t.Errorf("Expected 3 requests with 2 full responses, got %d and %d", requests, fullResponses)
+	}
+
+	// A 304 without a cached copy is still an error
+	client.SetCache(nil)
+	notModified := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotModified)
+	}))
+	defer notModified.Close()
+	if _, err := client.FetchFeed(notModified.URL); err == nil {
+		t.Error("Expected error for 304 without a cached feed")
+	}
+}
+
+func TestFetchMultipleFeedsConcurrency(t *testing.T) {
+	var mu sync.Mutex
+	inFlight := make(map[string]int)
+	maxInFlight := make(map[string]int)
+	total, maxTotal := 0, 0
+
+	handler := func(name string) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			mu.Lock()
+			inFlight[name]++
+			total++
+			maxInFlight[name] = max(maxInFlight[name], inFlight[name])
+			maxTotal = max(maxTotal, total)
+			mu.Unlock()
+
+			time.Sleep(20 * time.Millisecond)
+
+			mu.Lock()
+			inFlight[name]--
+			total--
+			mu.Unlock()
+
+			// Every item has the same date, so only the feed order decides the result order
+			w.Write([]byte(`<rss version="2.0"><channel><title>T</title><item><title>` + r.URL.Path +
+				`</title><pubDate>Mon, 02 Jan 2006 15:04:05 -0700</pubDate></item></channel></rss>`))
+		}
+	}
+	serverA := httptest.NewServer(handler("a"))
+	defer serverA.Close()
+	serverB := httptest.NewServer(handler("b"))
+	defer serverB.Close()
+
+	var feeds []FeedInfo
+	for i := 0; i < 6; i++ {
+		feeds = append(feeds, FeedInfo{Name: "A", URL: serverA.URL + "/" + strconv.Itoa(i)})
+	}
+	for i := 0; i < 4; i++ {
+		feeds = append(feeds, FeedInfo{Name: "B", URL: serverB.URL + "/" + strconv.Itoa(i)})
+	}
+	feeds = append(feeds, FeedInfo{Name: "Broken", URL: "http://127.0.0.1:1/feed"})
+
+	client := NewClient(5 * time.Second)
+	client.SetConcurrency(3, 2)
+	articles, _, err := client.FetchMultipleFeeds(feeds)
+	if err != nil {
+		t.Fatalf("FetchMultipleFeeds returned error: %v", err)
+	}
+
+	if maxInFlight["a"] > 2 || maxInFlight["b"] > 2 {
+		t.Errorf("Expected at most 2 requests per host, got %v", maxInFlight)
+	}
+	if maxTotal > 3 {
+		t.Errorf("Expected at most 3 concurrent requests, got %d", maxTotal)
+	}
+	if maxTotal < 2 {
+		t.Errorf("Expected feeds to be fetched in parallel, got %d at most", maxTotal)
+	}
+
+	if len(articles) != 10 {
+		t.Fatalf("Expected 10 articles, got %d", len(articles))
+	}
+	for i, article := range articles {
+		expected := "/" + strconv.Itoa(i)
+		if i >= 6 {
+			expected = "/" + strconv.Itoa(i-6)
+		}
+		if article.Title != expected || article.FeedName != feeds[i].Name {
+			t.Errorf("Article %d: expected %s from %s, got %s from %s", i, expected, feeds[i].Name, article.Title, article.FeedName)
+		}
+	}
+}
+
+func TestFetchMultipleFeedsResults(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/ok":
+			w.Write([]byte(`<rss version="2.0"><channel><title>OK</title><item><title>A</title></item><item><title>B</title></item></channel></rss>`))
+		case "/cached":
+			if r.Header.Get("If-None-Match") == `"1"` {
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+			w.Header().Set("ETag", `"1"`)
+			w.Write([]byte(`<rss version="2.0"><channel><title>Cached</title><item><title>C</title></item></channel></rss>`))
+		case "/garbage":
+			w.Write([]byte(`not a feed`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	feeds := []FeedInfo{
+		{Name: "OK", URL: server.URL + "/ok"},
+		{Name: "Cached", URL: server.URL + "/cached"},
+		{Name: "Missing", URL: server.URL + "/missing"},
+		{Name: "Garbage", URL: server.URL + "/garbage"},
+		{Name: "Unreachable", URL: "http://127.0.0.1:1/feed"},
+	}
+
+	client := NewClient(5 * time.Second)
+	client.SetCache(NewCache(nil))
+	if _, _, err := client.FetchMultipleFeeds(feeds); err != nil {
+		t.Fatalf("FetchMultipleFeeds returned error: %v", err)
+	}
+	articles, results, err := client.FetchMultipleFeeds(feeds)
+	if err != nil {
+		t.Fatalf("FetchMultipleFeeds returned error: %v", err)
+	}
+	if len(articles) != 3 {
+		t.Errorf("Expected 3 articles, got %d", len(articles))
+	}
+
+	expected := []struct {
+		status     FetchStatus
+		httpStatus int
+		items      int
+		failed     bool
+	}{
+		{FetchOK, 200, 2, false},
+		{FetchNotModified, 304, 1, false},
+		{FetchFailed, 404, 0, true},
+		{FetchFailed, 200, 0, true},
+		{FetchFailed, 0, 0, true},
+	}
+	if len(results) != len(expected) {
+		t.Fatalf("Expected %d results, got %d", len(expected), len(results))
+	}
+	for i, want := range expected {
+		got := results[i]
+		if !reflect.DeepEqual(got.Feed, feeds[i]) {
+			t.Errorf("Result %d: expected feed %+v, got %+v", i, feeds[i], got.Feed)
+		}
+		if got.Status != want.status || got.HTTPStatus != want.httpStatus || got.ItemCount != want.items || (got.Err != nil) != want.failed {
+			t.Errorf("Result %d (%s): expected %v/%d/%d items/failed=%v, got %v/%d/%d items/err=%v",
+				i, feeds[i].Name, want.status, want.httpStatus, want.items, want.failed, got.Status, got.HTTPStatus, got.ItemCount, got.Err)
+		}
+		if got.Duration <= 0 {
+			t.Errorf("Result %d: expected a duration to be recorded", i)
+		}
+	}
+
+	var httpErr *HTTPError
+	if !errors.As(results[2].Err, &httpErr) || httpErr.StatusCode != 404 {
+		t.Errorf("Expected HTTPError 404, got %v", results[2].Err)
+	}
+
+	// Every feed failing is still reported as an error alongside the results
+	_, results, err = client.FetchMultipleFeeds(feeds[2:])
+	if err == nil || len(results) != 3 {
+		t.Errorf("Expected an error and 3 results when all feeds fail, got %v and %d results", err, len(results))
+	}
+}
+
+func TestFetchMultipleFeedsContext(t *testing.T) {
+	started := make(chan struct{}, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		started <- struct{}{}
+		// Hang until the client gives up
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	var feeds []FeedInfo
+	for i := 0; i < 4; i++ {
+		feeds = append(feeds, FeedInfo{Name: strconv.Itoa(i), URL: server.URL + "/" + strconv.Itoa(i)})
+	}
+
+	client := NewClient(30 * time.Second)
+	client.SetConcurrency(2, 2)
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-started
+		cancel()
+	}()
+
+	start := time.Now()
+	articles, results, err := client.FetchMultipleFeedsContext(ctx, feeds)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected context.Canceled, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Expected cancellation to stop the fetch promptly, took %v", elapsed)
+	}
+	if articles != nil {
+		t.Errorf("Expected no articles from a cancelled fetch, got %d", len(articles))
+	}
+	if len(results) != len(feeds) {
+		t.Fatalf("Expected %d results, got %d", len(feeds), len(results))
+	}
+	for i, result := range results {
+		if result.Status != FetchFailed || !errors.Is(result.Err, context.Canceled) {
+			t.Errorf("Result %d: expected a cancelled failure, got %v: %v", i, result.Status, result.Err)
+		}
+	}
+
+	// Requests for a single feed are cancelled too
+	ctx, cancel = context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := client.FetchFeedContext(ctx, server.URL); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected context.DeadlineExceeded, got %v", err)
+	}
+}
+
+func TestFetchRetry(t *testing.T) {
+	okXML := `<rss version="2.0"><channel><title>T</title><item><title>A</title></item></channel></rss>`
+	requests := make(map[string]int)
+	var mu sync.Mutex
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests[r.URL.Path]++
+		n := requests[r.URL.Path]
+		mu.Unlock()
+
+		switch r.URL.Path {
+		case "/flaky":
+			if n <= 2 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			w.Write([]byte(okXML))
+		case "/throttled":
+			w.Header().Set("Retry-After", "120")
+			w.WriteHeader(http.StatusTooManyRequests)
+		case "/missing":
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient(5 * time.Second)
+	client.SetRetryPolicy(RetryPolicy{MaxRetries: 2, BaseDelay: time.Millisecond, MaxDelay: 10 * time.Millisecond})
+
+	// Transient errors are retried
+	feed, err := client.FetchFeed(server.URL + "/flaky")
+	if err != nil {
+		t.Fatalf("Expected flaky feed to succeed after retries, got %v", err)
+	}
+	if len(feed.Entries) != 1 || requests["/flaky"] != 3 {
+		t.Errorf("Expected 3 requests and 1 entry, got %d requests and %d entries", requests["/flaky"], len(feed.Entries))
+	}
+
+	// Client errors are not
+	if _, err := client.FetchFeed(server.URL + "/missing"); err == nil {
+		t.Error("Expected error for missing feed")
+	}
+	if requests["/missing"] != 1 {
+		t.Errorf("Expected 404 not to be retried, got %d requests", requests["/missing"])
+	}
+
+	// A Retry-After beyond the maximum delay ends the retries and defers the feed
+	before := time.Now()
+	_, results, _ := client.FetchMultipleFeeds([]FeedInfo{{Name: "Throttled", URL: server.URL + "/throttled"}})
+	if requests["/throttled"] != 1 {
+		t.Errorf("Expected long Retry-After not to be retried, got %d requests", requests["/throttled"])
+	}
+	var httpErr *HTTPError
+	if !errors.As(results[0].Err, &httpErr) || httpErr.RetryAfter != 2*time.Minute {
+		t.Errorf("Expected HTTPError with Retry-After of 2m, got %v", results[0].Err)
+	}
+	if results[0].NextAttempt.Before(before.Add(2 * time.Minute)) {
+		t.Errorf("Expected next attempt to honor Retry-After, got %v", results[0].NextAttempt)
+	}
+	_, results, _ = client.FetchMultipleFeeds([]FeedInfo{{Name: "Throttled", URL: server.URL + "/throttled"}})
+	if results[0].Status != FetchSkipped || requests["/throttled"] != 1 {
+		t.Errorf("Expected throttled feed to be skipped, got %v after %d requests", results[0].Status, requests["/throttled"])
+	}
+}
+
+func TestFeedFailureBackoff(t *testing.T) {
+	var failing bool
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if failing {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("ETag", `"1"`)
+		w.Write([]byte(`<rss version="2.0"><channel><title>T</title><item><title>A</title></item></channel></rss>`))
+	}))
+	defer server.Close()
+
+	client := NewClient(5 * time.Second)
+	client.SetRetryPolicy(RetryPolicy{})
+	client.SetCache(NewCache(nil))
+	feeds := []FeedInfo{{Name: "Feed", URL: server.URL}}
+
+	if _, _, err := client.FetchMultipleFeeds(feeds); err != nil {
+		t.Fatalf("FetchMultipleFeeds returned error: %v", err)
+	}
+
+	// Cached articles are kept while the feed fails
+	failing = true
+	articles, results, err := client.FetchMultipleFeeds(feeds)
+	if err != nil || len(articles) != 1 {
+		t.Fatalf("Expected cached article despite failure, got %d articles and %v", len(articles), err)
+	}
+	if results[0].Status != FetchFailed || results[0].Failures != 1 {
+		t.Errorf("Expected first failure, got %v with %d failures", results[0].Status, results[0].Failures)
+	}
+	if results[0].NextAttempt.After(time.Now()) {
+		t.Errorf("Expected no backoff after a single failure, got %v", results[0].NextAttempt)
+	}
+
+	_, results, _ = client.FetchMultipleFeeds(feeds)
+	if results[0].Failures != 2 || time.Until(results[0].NextAttempt) < 2*time.Minute {
+		t.Errorf("Expected backoff after 2 failures, got %d failures and next attempt %v", results[0].Failures, results[0].NextAttempt)
+	}
+
+	// Backed off feeds are skipped but still contribute cached articles
+	requests = 0
+	articles, results, _ = client.FetchMultipleFeeds(feeds)
+	if requests != 0 {
+		t.Errorf("Expected backed off feed not to be requested, got %d requests", requests)
+	}
+	if results[0].Status != FetchSkipped || results[0].Err == nil || len(articles) != 1 {
+		t.Errorf("Expected skipped feed with cached article, got %v, %v and %d articles", results[0].Status, results[0].Err, len(articles))
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	testCases := []struct {
+		input    string
+		expected time.Duration
+	}{
+		{"", 0},
+		{"30", 30 * time.Second},
+		{"-5", 0},
+		{"Mon, 01 Jan 2024 12:01:00 GMT", time.Minute},
+		{"Mon, 01 Jan 2024 11:00:00 GMT", 0},
+		{"soon", 0},
+	}
+
+	for _, tc := range testCases {
+		if got := parseRetryAfter(tc.input, now); got != tc.expected {
+			t.Errorf("parseRetryAfter(%q) = %v, expected %v", tc.input, got, tc.expected)
+		}
+	}
+}
+
+func TestFetchRedirectsAndGone(t *testing.T) {
+	var goneRequests, deadRequests int
+	mux := http.NewServeMux()
+	mux.HandleFunc("/new", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<rss version="2.0"><channel><title>New</title><item><title>A</title></item></channel></rss>`))
+	})
+	mux.HandleFunc("/moved", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/new", http.StatusMovedPermanently)
+	})
+	mux.HandleFunc("/moved-twice", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/moved", http.StatusPermanentRedirect)
+	})
+	mux.HandleFunc("/temporary", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/new", http.StatusFound)
+	})
+	mux.HandleFunc("/mixed", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/temporary", http.StatusMovedPermanently)
+	})
+	mux.HandleFunc("/gone", func(w http.ResponseWriter, r *http.Request) {
+		goneRequests++
+		w.WriteHeader(http.StatusGone)
+	})
+	mux.HandleFunc("/dead", func(w http.ResponseWriter, r *http.Request) {
+		deadRequests++
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	feeds := []FeedInfo{
+		{Name: "Moved", URL: server.URL + "/moved"},
+		{Name: "Moved twice", URL: server.URL + "/moved-twice"},
+		{Name: "Temporary", URL: server.URL + "/temporary"},
+		{Name: "Mixed", URL: server.URL + "/mixed"},
+		{Name: "Gone", URL: server.URL + "/gone"},
+		{Name: "Dead", URL: server.URL + "/dead", Dead: true},
+	}
+
+	client := NewClient(5 * time.Second)
+	articles, results, err := client.FetchMultipleFeeds(feeds)
+	if err != nil {
+		t.Fatalf("FetchMultipleFeeds returned error: %v", err)
+	}
+	if len(articles) != 4 {
+		t.Errorf("Expected 4 articles, got %d", len(articles))
+	}
+
+	expectedMoves := []string{server.URL + "/new", server.URL + "/new", "", ""}
+	for i, expected := range expectedMoves {
+		if results[i].MovedTo != expected {
+			t.Errorf("%s: expected MovedTo %q, got %q", feeds[i].Name, expected, results[i].MovedTo)
+		}
+	}
+
+	if results[4].Status != FetchGone || goneRequests != 1 {
+		t.Errorf("Expected 410 to be reported as gone without retries, got %v after %d requests", results[4].Status, goneRequests)
+	}
+	if results[5].Status != FetchGone || !errors.Is(results[5].Err, ErrFeedGone) || deadRequests != 0 {
+		t.Errorf("Expected dead feed not to be fetched, got %v: %v after %d requests", results[5].Status, results[5].Err, deadRequests)
+	}
+
+	// A feed that just went away is not a failed refresh
+	if _, results, err := client.FetchMultipleFeeds([]FeedInfo{{Name: "Gone", URL: server.URL + "/gone"}}); err != nil || results[0].Status != FetchGone {
+		t.Errorf("Expected a newly gone feed not to fail the refresh, got %v", err)
+	}
+}
+
+func TestFeedOptions(t *testing.T) {
+	t.Setenv("RSSS_TEST_PASSWORD", "s3cret$")
+	t.Setenv("RSSS_TEST_TOKEN", "tok")
+
+	var requests int
+	var last *http.Request
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		last = r
+		w.Write([]byte(`<rss version="2.0"><channel><title>Private</title></channel></rss>`))
+	}))
+	defer server.Close()
+
+	client := NewClient(5 * time.Second)
+	feeds := []FeedInfo{{
+		Name:      "Basic",
+		URL:       server.URL,
+		Headers:   map[string]string{"X-Api-Key": "key-${RSSS_TEST_TOKEN}", "Accept": "application/rss+xml"},
+		UserAgent: "rsss-test/1.0",
+		Auth:      &FeedAuth{Type: AuthBasic, Username: "alice", Password: "${RSSS_TEST_PASSWORD}"},
+		Cookies:   map[string]string{"session": "${RSSS_TEST_TOKEN}", "theme": "dark"},
+	}}
+	if _, results, _ := client.FetchMultipleFeeds(feeds); results[0].Err != nil {
+		t.Fatalf("Fetch returned error: %v", results[0].Err)
+	}
+
+	if user, pass, ok := last.BasicAuth(); !ok || user != "alice" || pass != "s3cret$" {
+		t.Errorf("Expected basic auth alice:s3cret$, got %q:%q", user, pass)
+	}
+	if got := last.Header.Get("X-Api-Key"); got != "key-tok" {
+		t.Errorf("Expected expanded X-Api-Key header, got %q", got)
+	}
+	if got := last.Header.Get("Accept"); got != "application/rss+xml" {
+		t.Errorf("Expected Accept header, got %q", got)
+	}
+	if got := last.UserAgent(); got != "rsss-test/1.0" {
+		t.Errorf("Expected user agent override, got %q", got)
+	}
+	if got := last.Header.Get("Cookie"); got != "session=tok; theme=dark" {
+		t.Errorf("Expected cookies, got %q", got)
+	}
+
+	feeds = []FeedInfo{{Name: "Bearer", URL: server.URL, Auth: &FeedAuth{Type: "Bearer", Token: "${RSSS_TEST_TOKEN}"}}}
+	if _, results, _ := client.FetchMultipleFeeds(feeds); results[0].Err != nil {
+		t.Fatalf("Fetch returned error: %v", results[0].Err)
+	}
+	if got := last.Header.Get("Authorization"); got != "Bearer tok" {
+		t.Errorf("Expected bearer token, got %q", got)
+	}
+
+	// Credentials can come from a credential store
+	client.SetCredentials(testCredentials{"jira": {"store-user", "store-pass"}})
+	feeds = []FeedInfo{
+		{Name: "Stored", URL: server.URL, Auth: &FeedAuth{Type: AuthBasic, Credential: "jira"}},
+		{Name: "Override", URL: server.URL + "/override", Auth: &FeedAuth{Type: AuthBasic, Username: "carol", Credential: "jira"}},
+	}
+	for _, feed := range feeds {
+		if _, results, _ := client.FetchMultipleFeeds([]FeedInfo{feed}); results[0].Err != nil {
+			t.Fatalf("%s: fetch returned error: %v", feed.Name, results[0].Err)
+		}
+		user, pass, _ := last.BasicAuth()
+		expectedUser := "store-user"
+		if feed.Auth.Username != "" {
+			expectedUser = feed.Auth.Username
+		}
+		if user != expectedUser || pass != "store-pass" {
+			t.Errorf("%s: expected %s:store-pass, got %s:%s", feed.Name, expectedUser, user, pass)
+		}
+	}
+
+	// Broken options fail without sending a request
+	requests = 0
+	feeds = []FeedInfo{
+		{Name: "Missing", URL: server.URL + "/missing", Headers: map[string]string{"X-Api-Key": "${RSSS_TEST_UNSET}"}},
+		{Name: "Unknown", URL: server.URL + "/unknown", Auth: &FeedAuth{Type: "digest"}},
+		{Name: "Unknown credential", URL: server.URL + "/unknown-credential", Auth: &FeedAuth{Type: AuthBearer, Credential: "gitlab"}},
+	}
+	_, results, _ := client.FetchMultipleFeeds(feeds)
+	if results[0].Err == nil || !strings.Contains(results[0].Err.Error(), "RSSS_TEST_UNSET is not set") {
+		t.Errorf("Expected unset variable error, got %v", results[0].Err)
+	}
+	if results[1].Err == nil || !strings.Contains(results[1].Err.Error(), `unsupported auth type "digest"`) {
+		t.Errorf("Expected unsupported auth type error, got %v", results[1].Err)
+	}
+	if results[2].Err == nil || !strings.Contains(results[2].Err.Error(), `unknown credential "gitlab"`) {
+		t.Errorf("Expected unknown credential error, got %v", results[2].Err)
+	}
+	if requests != 0 {
+		t.Errorf("Expected no requests for broken options, got %d", requests)
+	}
+}
+
+// testCredentials is a CredentialResolver backed by a map of username and secret pairs
+type testCredentials map[string][2]string
+
+func (c testCredentials) Credential(name string) (string, string, error) {
+	cred, ok := c[name]
+	if !ok {
+		return "", "", fmt.Errorf("unknown credential %q", name)
+	}
+	return cred[0], cred[1], nil
+}
+
+func TestFetchThroughProxy(t *testing.T) {
+	const feedXML = `<rss version="2.0"><channel><title>Proxied</title></channel></rss>`
+
+	var proxied []string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied = append(proxied, r.URL.String())
+		w.Write([]byte(feedXML))
+	}))
+	defer proxy.Close()
+
+	origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(feedXML))
+	}))
+	defer origin.Close()
+
+	client := NewClient(5 * time.Second)
+	if err := client.SetTransport(TransportOptions{Proxy: proxy.URL, NoProxy: []string{"127.0.0.1"}}); err != nil {
+		t.Fatalf("SetTransport returned error: %v", err)
+	}
+
+	// Only reachable through the proxy
+	if _, err := client.FetchFeed("http://feeds.example.invalid/rss"); err != nil {
+		t.Fatalf("Fetch through proxy returned error: %v", err)
+	}
+	if len(proxied) != 1 || proxied[0] != "http://feeds.example.invalid/rss" {
+		t.Errorf("Expected request for the feed through the proxy, got %v", proxied)
+	}
+	if transport, ok := client.Transport().(*http.Transport); !ok || transport == http.DefaultTransport || transport.Proxy == nil {
+		t.Errorf("Expected the configured transport to be shared, got %v", client.Transport())
+	}
+
+	// Bypassed hosts are fetched directly
+	if _, err := client.FetchFeed(origin.URL); err != nil {
+		t.Fatalf("Direct fetch returned error: %v", err)
+	}
+	if len(proxied) != 1 {
+		t.Errorf("Expected bypassed host not to use the proxy, got %v", proxied)
+	}
+
+	// A feed can override the proxy
+	feeds := []FeedInfo{{Name: "Direct", URL: origin.URL + "/direct", Transport: &TransportOptions{Proxy: ProxyDirect}}}
+	if _, results, _ := client.FetchMultipleFeeds(feeds); results[0].Err != nil {
+		t.Fatalf("Fetch with direct override returned error: %v", results[0].Err)
+	}
+	feeds = []FeedInfo{{Name: "Proxied", URL: origin.URL + "/proxied", Transport: &TransportOptions{NoProxy: []string{"other.example"}}}}
+	if _, results, _ := client.FetchMultipleFeeds(feeds); results[0].Err != nil {
+		t.Fatalf("Fetch with no_proxy override returned error: %v", results[0].Err)
+	}
+	if len(proxied) != 2 || proxied[1] != origin.URL+"/proxied" {
+		t.Errorf("Expected overridden no_proxy to route through the proxy, got %v", proxied)
+	}
+
+	if err := client.SetTransport(TransportOptions{Proxy: "ftp://proxy.example"}); err == nil {
+		t.Error("Expected error for unsupported proxy scheme")
+	}
+	if err := client.SetTransport(TransportOptions{Proxy: "socks5://127.0.0.1:1080"}); err != nil {
+		t.Errorf("Expected SOCKS5 proxy to be accepted, got %v", err)
+	}
+}
+
+func TestFetchCustomCA(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<rss version="2.0"><channel><title>Internal</title></channel></rss>`))
+	}))
+	defer server.Close()
+
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	if err := os.WriteFile(caFile, certPEM, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	client := NewClient(5 * time.Second)
+	client.SetRetryPolicy(RetryPolicy{})
+	if _, err := client.FetchFeed(server.URL); err == nil {
+		t.Fatal("Expected certificate error without the CA")
+	}
+
+	feeds := []FeedInfo{{Name: "Internal", URL: server.URL + "/internal", Transport: &TransportOptions{CAFile: caFile}}}
+	if _, results, _ := client.FetchMultipleFeeds(feeds); results[0].Err != nil {
+		t.Fatalf("Fetch with per-feed CA returned error: %v", results[0].Err)
+	}
+
+	if err := client.SetTransport(TransportOptions{CAFile: caFile}); err != nil {
+		t.Fatalf("SetTransport returned error: %v", err)
+	}
+	if _, err := client.FetchFeed(server.URL); err != nil {
+		t.Errorf("Fetch with global CA returned error: %v", err)
+	}
+
+	if err := client.SetTransport(TransportOptions{CertFile: caFile}); err == nil {
+		t.Error("Expected error for client certificate without key")
+	}
+}
+
+func TestBypassProxy(t *testing.T) {
+	patterns := []string{"corp.example", "*.internal", "localhost:8080", "10.0.0.0/8"}
+	tests := []struct {
+		url  string
+		want bool
+	}{
+		{"https://corp.example/feed", true},
+		{"https://news.corp.example/feed", true},
+		{"https://notcorp.example/feed", false},
+		{"https://a.internal/feed", true},
+		{"https://internal/feed", false},
+		{"http://localhost:8080/feed", true},
+		{"http://localhost/feed", false},
+		{"http://10.1.2.3/feed", true},
+		{"http://192.168.1.1/feed", false},
+	}
+	for _, tt := range tests {
+		u, _ := url.Parse(tt.url)
+		if got := bypassProxy(u, patterns); got != tt.want {
+			t.Errorf("bypassProxy(%s) = %v, want %v", tt.url, got, tt.want)
+		}
+	}
+	u, _ := url.Parse("https://anything.example")
+	if !bypassProxy(u, []string{"*"}) {
+		t.Error("Expected * to bypass every host")
+	}
+}
+
+func TestFetchCompressedAndLimited(t *testing.T) {
+	var items strings.Builder
+	for i := 0; i < 500; i++ {
+		fmt.Fprintf(&items, "<item><title>Item %d</title><link>https://example.com/%d</link></item>", i, i)
+	}
+	feedXML := `<?xml version="1.0"?><rss version="2.0"><channel><title>Big</title>` + items.String() + `</channel></rss>`
+
+	var acceptEncoding string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		acceptEncoding = r.Header.Get("Accept-Encoding")
+		switch r.URL.Path {
+		case "/gzip":
+			w.Header().Set("Content-Encoding", "gzip")
+			gz := gzip.NewWriter(w)
+			gz.Write([]byte(feedXML))
+			gz.Close()
+		case "/zlib":
+			w.Header().Set("Content-Encoding", "deflate")
+			zw := zlib.NewWriter(w)
+			zw.Write([]byte(feedXML))
+			zw.Close()
+		case "/deflate":
+			w.Header().Set("Content-Encoding", "deflate")
+			fw, _ := flate.NewWriter(w, flate.DefaultCompression)
+			fw.Write([]byte(feedXML))
+			fw.Close()
+		case "/brotli":
+			w.Header().Set("Content-Encoding", "br")
+			w.Write([]byte(feedXML))
+		case "/chunked":
+			// No Content-Length, so the limit is only hit while reading
+			for i := 0; i < len(feedXML); i += 1000 {
+				w.Write([]byte(feedXML[i:min(i+1000, len(feedXML))]))
+				w.(http.Flusher).Flush()
+			}
+		default:
+			w.Write([]byte(feedXML))
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient(5 * time.Second)
+	for _, path := range []string{"/gzip", "/zlib", "/deflate", "/plain"} {
+		feed, err := client.FetchFeed(server.URL + path)
+		if err != nil {
+			t.Fatalf("%s: FetchFeed returned error: %v", path, err)
+		}
+		if len(feed.Entries) != 500 || feed.Format != "rss" {
+			t.Errorf("%s: expected 500 rss entries, got %d %s", path, len(feed.Entries), feed.Format)
+		}
+	}
+	if acceptEncoding != "gzip, deflate" {
+		t.Errorf("Expected gzip and deflate to be accepted, got %q", acceptEncoding)
+	}
+
+	if _, err := client.FetchFeed(server.URL + "/brotli"); err == nil || !strings.Contains(err.Error(), "unsupported content encoding") {
+		t.Errorf("Expected unsupported encoding error, got %v", err)
+	}
+
+	client.SetMaxBodySize(int64(len(feedXML) - 1))
+	for _, path := range []string{"/plain", "/chunked", "/gzip"} {
+		if _, err := client.FetchFeed(server.URL + path); !errors.Is(err, ErrBodyTooLarge) {
+			t.Errorf("%s: expected ErrBodyTooLarge, got %v", path, err)
+		}
+	}
+	client.SetMaxBodySize(int64(len(feedXML)))
+	if _, err := client.FetchFeed(server.URL + "/chunked"); err != nil {
+		t.Errorf("Expected feed of exactly the limit to be read, got %v", err)
+	}
+}
+
+func TestParseFeedReader(t *testing.T) {
+	var items strings.Builder
+	for i := 0; i < 1000; i++ {
+		fmt.Fprintf(&items, "<item><title>Caf\xe9 %d</title></item>", i)
+	}
+	body := `<?xml version="1.0" encoding="ISO-8859-1"?><rss version="2.0"><channel><title>` + "Caf\xe9" + `</title>` + items.String() + `</channel></rss>`
+
+	feed, err := ParseFeedReader("application/rss+xml; charset=ISO-8859-1", strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("ParseFeedReader returned error: %v", err)
+	}
+	if feed.Title != "Café" || len(feed.Entries) != 1000 || feed.Entries[999].Title != "Café 999" {
+		t.Errorf("Unexpected feed %q with %d entries", feed.Title, len(feed.Entries))
+	}
+
+	// Read errors are reported as such rather than as malformed documents
+	failing := io.MultiReader(strings.NewReader(body[:sniffLen+100]), iotest.ErrReader(errors.New("connection reset")))
+	if _, err := ParseFeedReader("application/rss+xml", failing); err == nil || !strings.Contains(err.Error(), "failed to read response body: connection reset") {
+		t.Errorf("Expected read error, got %v", err)
+	}
+
+	if _, err := ParseFeedReader("text/html", strings.NewReader("<html><body>Not a feed</body></html>")); !errors.Is(err, ErrUnknownFormat) {
+		t.Errorf("Expected ErrUnknownFormat, got %v", err)
+	}
+}
+
+func TestLocalSources(t *testing.T) {
+	dir := t.TempDir()
+	feedXML := `<rss version="2.0"><channel><title>Local</title><item><title>One</title></item></channel></rss>`
+	path := filepath.Join(dir, "my feed.xml")
+	if err := os.WriteFile(path, []byte(feedXML), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	client := NewClient(5 * time.Second)
+	feed, err := client.FetchFeed("file://" + strings.ReplaceAll(path, " ", "%20"))
+	if err != nil {
+		t.Fatalf("FetchFeed(file://) returned error: %v", err)
+	}
+	if feed.Title != "Local" || len(feed.Entries) != 1 {
+		t.Errorf("Unexpected file feed %+v", feed)
+	}
+	if _, err := client.FetchFeed("file://" + filepath.Join(dir, "missing.xml")); err == nil {
+		t.Error("Expected error for missing file")
+	}
+
+	feed, err = client.FetchFeed("exec:cat '" + path + "' | sed 's/Local/Generated/'")
+	if err != nil {
+		t.Fatalf("FetchFeed(exec:) returned error: %v", err)
+	}
+	if feed.Title != "Generated" {
+		t.Errorf("Expected command output to be parsed, got %q", feed.Title)
+	}
+
+	if _, err := client.FetchFeed("exec:echo 'no such thing' >&2; exit 3"); err == nil || !strings.Contains(err.Error(), "no such thing") {
+		t.Errorf("Expected command error with stderr, got %v", err)
+	}
+
+	feeds, err := client.Discover("file://" + path)
+	if err != nil || len(feeds) != 1 || feeds[0].Title != "Local" {
+		t.Errorf("Expected local feed to be discovered as itself, got %v, %v", feeds, err)
+	}
+
+	client.SetCommandTimeout(100 * time.Millisecond)
+	start := time.Now()
+	if _, err := client.FetchFeed("exec:sleep 5"); err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Errorf("Expected timeout error, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 3*time.Second {
+		t.Errorf("Expected command to be killed at the timeout, took %v", elapsed)
+	}
+
+	client.SetMaxBodySize(10)
+	if _, err := client.FetchFeed("exec:yes"); !errors.Is(err, ErrBodyTooLarge) {
+		t.Errorf("Expected ErrBodyTooLarge for endless output, got %v", err)
+	}
+}
+
+func TestFeedFilter(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api":
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"releases": ["v1.0", "v1.1"]}`))
+		default:
+			w.Write([]byte(`<rss version="2.0"><channel><title>Broken &nbsp; feed</title></channel></rss>`))
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient(5 * time.Second)
+	feeds := []FeedInfo{
+		{Name: "Fixed", URL: server.URL + "/broken", Filter: "sed 's/&nbsp;/ /'"},
+		{Name: "API", URL: server.URL + "/api", Filter: `sed 's/.*/<rss version="2.0"><channel><title>Releases<\/title><\/channel><\/rss>/'`},
+		{Name: "Failing", URL: server.URL + "/failing", Filter: "echo 'cannot convert' >&2; exit 1"},
+		{Name: "Unfiltered", URL: server.URL + "/unfiltered"},
+	}
+	_, results, _ := client.FetchMultipleFeeds(feeds)
+
+	if results[0].Err != nil || results[0].Status != FetchOK {
+		t.Errorf("Expected filtered feed to parse, got %v", results[0].Err)
+	}
+	if results[1].Err != nil {
+		t.Errorf("Expected JSON converted by the filter to parse as RSS, got %v", results[1].Err)
+	}
+	if err := results[2].Err; err == nil || !strings.Contains(err.Error(), "filter: command failed") || !strings.Contains(err.Error(), "cannot convert") {
+		t.Errorf("Expected filter error with stderr, got %v", err)
+	}
+	if results[3].Err == nil {
+		t.Error("Expected unfiltered broken feed to fail")
+	}
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "feed.xml")
+	if err := os.WriteFile(path, []byte(`<rss version="2.0"><channel><title>File</title></channel></rss>`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	feeds = []FeedInfo{
+		{Name: "File", URL: "file://" + path, Filter: "sed 's/File/Filtered/'"},
+		{Name: "Exec", URL: "exec:cat " + path, Filter: "sed 's/File/Exec/'"},
+	}
+	_, results, _ = client.FetchMultipleFeeds(feeds)
+	for _, result := range results {
+		if result.Err != nil {
+			t.Errorf("%s: filter returned error: %v", result.Feed.Name, result.Err)
+		}
+	}
+
+	client.SetCommandTimeout(100 * time.Millisecond)
+	feed := FeedInfo{Name: "Slow", URL: server.URL + "/slow", Filter: "sleep 5"}
+	if _, results, _ := client.FetchMultipleFeeds([]FeedInfo{feed}); results[0].Err == nil || !strings.Contains(results[0].Err.Error(), "timed out") {
+		t.Errorf("Expected filter timeout, got %v", results[0].Err)
+	}
+}
+
+func TestScrapeFeed(t *testing.T) {
+	page := "<!DOCTYPE html><html><head><meta charset=\"iso-8859-1\"><title>Team news</title></head><body>" +
+		"<script>var x = '<article>';</script>" +
+		`<article class="post"><h2><a href="/posts/1">First <em>post</em></a></h2><time datetime="2024-03-01T10:00:00Z">March 1</time><p class="lead">Hello   there</p></article>` +
+		"<article class=\"post\"><h2><a href=\"posts/2\">Caf\xe9 opening</a></h2><time datetime=\"2024-03-02T10:00:00Z\">March 2</time><p class=\"lead\">News</p></article>" +
+		`<article class="post"><h2><a href="https://other.example/3" data-id="x@3">Elsewhere</a></h2></article>` +
+		"</body></html>"
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(page))
+	}))
+	defer server.Close()
+
+	client := NewClient(5 * time.Second)
+	feed := FeedInfo{
+		Name: "Team",
+		URL:  server.URL + "/blog/",
+		Scrape: &ScrapeOptions{
+			Item:    "article.post",
+			Title:   "h2",
+			Link:    "h2 a",
+			Date:    "time",
+			Summary: "p.lead",
+		},
+	}
+	articles, results, err := client.FetchMultipleFeeds([]FeedInfo{feed})
+	if err != nil || results[0].Err != nil {
+		t.Fatalf("Scraping returned error: %v, %v", err, results[0].Err)
+	}
+	if len(articles) != 3 {
+		t.Fatalf("Expected 3 articles, got %d", len(articles))
+	}
+
+	byTitle := make(map[string]Article)
+	for _, a := range articles {
+		byTitle[a.Title] = a
+	}
+	first := byTitle["First post"]
+	if first.Link != server.URL+"/posts/1" || first.Description != "Hello there" || first.FeedName != "Team" {
+		t.Errorf("Unexpected first article %+v", first)
+	}
+	if !first.PubDate.Equal(time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)) {
+		t.Errorf("Expected date from datetime attribute, got %v", first.PubDate)
+	}
+	if second, ok := byTitle["Café opening"]; !ok || second.Link != server.URL+"/blog/posts/2" {
+		t.Errorf("Expected relative link resolved against the page, got %+v", byTitle)
+	}
+	if third := byTitle["Elsewhere"]; third.Link != "https://other.example/3" {
+		t.Errorf("Expected absolute link kept, got %q", third.Link)
+	}
+
+	// Defaults and attribute selectors
+	parsed, err := scrape(&ScrapeOptions{Item: "h2 a", Title: "@data-id"}, "https://example.com/", "text/html", strings.NewReader(page))
+	if err != nil {
+		t.Fatalf("scrape returned error: %v", err)
+	}
+	if parsed.Title != "Team news" || parsed.Format != ScrapeFormat || len(parsed.Entries) != 3 {
+		t.Fatalf("Unexpected scraped feed %+v", parsed)
+	}
+	if e := parsed.Entries[2]; e.Title != "x@3" || e.Link != "https://other.example/3" {
+		t.Errorf("Expected attribute title and item href, got %+v", e)
+	}
+
+	for _, opts := range []*ScrapeOptions{{Item: ""}, {Item: "article["}, {Item: "article", Date: "time["}, {Item: "section.missing"}} {
+		if _, err := scrape(opts, "https://example.com/", "text/html", strings.NewReader(page)); err == nil {
+			t.Errorf("Expected error for %+v", opts)
+		}
+	}
+}
+
+func TestResolveURLs(t *testing.T) {
+	rssDoc := `<rss version="2.0"><channel><title>Blog</title><link>https://blog.example.com/</link>
+<item><title>Relative</title><link>/2024/post</link><comments>post#comments</comments>
+<description><![CDATA[<p>See <a href="/about">about</a> <img src="img/a.png" srcset="img/a.png 1x, https://cdn.example.com/a2.png 2x"></p>]]></description>
+<enclosure url="media/ep1.mp3" type="audio/mpeg" length="1"/></item>
+<item><title>Absolute</title><link>https://other.example/x</link><description><![CDATA[<a href="https://other.example/y">kept &amp; <b>as is</b></a>]]></description></item>
+</channel></rss>`
+	atomDoc := `<feed xmlns="http://www.w3.org/2005/Atom" xml:base="https://atom.example.com/base/">
+<title>Atom</title><link href="https://atom.example.com/"/>
+<entry xml:base="entries/"><id>1</id><title>Nested</title><link href="one"/><content type="html">&lt;img src="pic.png"&gt;</content></entry>
+<entry><id>2</id><title>Feed base</title><link href="two"/></entry>
+</feed>`
+	bareDoc := `<rss version="2.0"><channel><title>Bare</title><item><title>Item</title><link>posts/1</link></item></channel></rss>`
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/feeds/rss":
+			w.Write([]byte(rssDoc))
+		case "/feeds/atom":
+			w.Write([]byte(atomDoc))
+		default:
+			w.Write([]byte(bareDoc))
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient(5 * time.Second)
+	feed, err := client.FetchFeed(server.URL + "/feeds/rss")
+	if err != nil {
+		t.Fatalf("FetchFeed returned error: %v", err)
+	}
+	e := feed.Entries[0]
+	if e.Link != "https://blog.example.com/2024/post" || e.CommentsURL != "https://blog.example.com/post#comments" {
+		t.Errorf("Expected links resolved against the channel link, got %q, %q", e.Link, e.CommentsURL)
+	}
+	if e.Enclosures[0].URL != "https://blog.example.com/media/ep1.mp3" {
+		t.Errorf("Expected enclosure resolved, got %q", e.Enclosures[0].URL)
+	}
+	for _, want := range []string{
+		`href="https://blog.example.com/about"`,
+		`src="https://blog.example.com/img/a.png"`,
+		`srcset="https://blog.example.com/img/a.png 1x, https://cdn.example.com/a2.png 2x"`,
+	} {
+		if !strings.Contains(e.Description, want) {
+			t.Errorf("Expected description to contain %s, got %s", want, e.Description)
+		}
+	}
+	if want := `<a href="https://other.example/y">kept &amp; <b>as is</b></a>`; feed.Entries[1].Description != want {
+		t.Errorf("Expected absolute content unchanged, got %s", feed.Entries[1].Description)
+	}
+
+	feed, err = client.FetchFeed(server.URL + "/feeds/atom")
+	if err != nil {
+		t.Fatalf("FetchFeed returned error: %v", err)
+	}
+	if got := feed.Entries[0].Link; got != "https://atom.example.com/base/entries/one" {
+		t.Errorf("Expected nested xml:base, got %q", got)
+	}
+	if got := feed.Entries[0].Content; got != `<img src="https://atom.example.com/base/entries/pic.png">` {
+		t.Errorf("Expected content resolved against xml:base, got %q", got)
+	}
+	if got := feed.Entries[1].Link; got != "https://atom.example.com/base/two" {
+		t.Errorf("Expected feed xml:base, got %q", got)
+	}
+
+	feed, err = client.FetchFeed(server.URL + "/feeds/bare")
+	if err != nil {
+		t.Fatalf("FetchFeed returned error: %v", err)
+	}
+	if got := feed.Entries[0].Link; got != server.URL+"/feeds/posts/1" {
+		t.Errorf("Expected link resolved against the feed URL, got %q", got)
+	}
+
+	// IDs stay derived from the link as written so that seen articles are kept
+	parsed, _ := ParseFeed("application/rss+xml", []byte(bareDoc))
+	if parsed.Entries[0].ID != "posts/1" || feed.Entries[0].ID != "posts/1" {
+		t.Errorf("Expected unresolved ID, got %q and %q", parsed.Entries[0].ID, feed.Entries[0].ID)
+	}
+}
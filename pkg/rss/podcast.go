@@ -0,0 +1,76 @@
+package rss
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MediaContent represents a Media RSS media:content element. FileSize and
+// Duration are parsed leniently, as feeds often get them wrong.
+type MediaContent struct {
+	URL      string `xml:"url,attr"`
+	Type     string `xml:"type,attr"`
+	FileSize string `xml:"fileSize,attr"`
+	Duration string `xml:"duration,attr"`
+}
+
+// MediaGroup represents a Media RSS media:group element
+type MediaGroup struct {
+	Contents []MediaContent `xml:"http://search.yahoo.com/mrss/ content"`
+}
+
+// ITunesImage represents an itunes:image element
+type ITunesImage struct {
+	Href string `xml:"href,attr"`
+}
+
+// parseDuration parses an iTunes duration given as seconds, MM:SS or HH:MM:SS
+func parseDuration(value string) time.Duration {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0
+	}
+
+	var seconds float64
+	for _, part := range strings.Split(value, ":") {
+		n, err := strconv.ParseFloat(part, 64)
+		if err != nil {
+			return 0
+		}
+		seconds = seconds*60 + n
+	}
+	return time.Duration(seconds * float64(time.Second))
+}
+
+// mergeEnclosures combines RSS enclosures with Media RSS content, skipping duplicate URLs
+func mergeEnclosures(enclosures []Enclosure, media []MediaContent) []Enclosure {
+	merged := append([]Enclosure(nil), enclosures...)
+	seen := make(map[string]bool)
+	for _, enclosure := range enclosures {
+		seen[enclosure.URL] = true
+	}
+
+	for _, content := range media {
+		if content.URL == "" || seen[content.URL] {
+			continue
+		}
+		seen[content.URL] = true
+		merged = append(merged, Enclosure{
+			URL:    content.URL,
+			Type:   content.Type,
+			Length: parseLength(content.FileSize),
+		})
+	}
+	return merged
+}
+
+// mediaDuration returns the duration of the first media:content that declares one
+func mediaDuration(media []MediaContent) time.Duration {
+	for _, content := range media {
+		if duration := parseDuration(content.Duration); duration > 0 {
+			return duration
+		}
+	}
+	return 0
+}
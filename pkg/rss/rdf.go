@@ -0,0 +1,75 @@
+package rss
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+)
+
+// RDF represents the root element of an RSS 1.0 (RDF Site Summary) feed.
+// Unlike RSS 2.0, items are siblings of the channel rather than children.
+type RDF struct {
+	XMLName xml.Name   `xml:"http://www.w3.org/1999/02/22-rdf-syntax-ns# RDF"`
+	Channel RDFChannel `xml:"channel"`
+	Items   []RDFItem  `xml:"item"`
+}
+
+// RDFChannel represents an RSS 1.0 channel
+type RDFChannel struct {
+	Title       string `xml:"title"`
+	Link        string `xml:"link"`
+	Description string `xml:"description"`
+}
+
+// RDFItem represents an RSS 1.0 item
+type RDFItem struct {
+	About       string `xml:"http://www.w3.org/1999/02/22-rdf-syntax-ns# about,attr"`
+	Title       string `xml:"title"`
+	Link        string `xml:"link"`
+	Description string `xml:"description"`
+	Date        string `xml:"http://purl.org/dc/elements/1.1/ date"`
+	Creator     string `xml:"http://purl.org/dc/elements/1.1/ creator"`
+	Content     string `xml:"http://purl.org/rss/1.0/modules/content/ encoded"`
+}
+
+// isRDF reports whether the document is an RSS 1.0 (RDF) feed
+func isRDF(contentType string, body []byte) bool {
+	return hasRootElement(body, "RDF")
+}
+
+// parseRDF parses an RSS 1.0 document into a Feed
+func parseRDF(body []byte) (*Feed, error) {
+	return decodeRDF(bytes.NewReader(body))
+}
+
+// decodeRDF parses an RSS 1.0 document read from r into a Feed
+func decodeRDF(r io.Reader) (*Feed, error) {
+	var rdf RDF
+	if err := decodeXML(r, &rdf); err != nil {
+		return nil, err
+	}
+	return rdf.toFeed(), nil
+}
+
+// toFeed converts the RDF document into the format-neutral Feed model
+func (r *RDF) toFeed() *Feed {
+	feed := &Feed{
+		Title:       r.Channel.Title,
+		Link:        r.Channel.Link,
+		Description: r.Channel.Description,
+	}
+
+	for _, item := range r.Items {
+		feed.Entries = append(feed.Entries, Entry{
+			ID:          entryID(item.About, item.Link, item.Title, item.Date),
+			Title:       item.Title,
+			Link:        item.Link,
+			Description: item.Description,
+			PubDate:     entryDate(item.Date),
+			Content:     item.Content,
+			Author:      item.Creator,
+		})
+	}
+
+	return feed
+}
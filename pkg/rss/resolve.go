@@ -0,0 +1,157 @@
+package rss
+
+import (
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// ResolveURLs makes the links of a feed and its entries absolute, including
+// links and images in entry content. Relative URLs are resolved against the
+// xml:base of the document, then the feed's own link, and finally docURL,
+// the URL the document was fetched from, which may be empty.
+func (f *Feed) ResolveURLs(docURL string) {
+	var base *url.URL
+	if u, err := url.Parse(docURL); err == nil && docURL != "" && u.Opaque == "" {
+		base = u
+	}
+
+	switch {
+	case f.base != "":
+		xmlBase := resolveReference(base, f.base)
+		f.Link = resolveURL(xmlBase, f.Link)
+		base = xmlBase
+	case f.Link != "":
+		f.Link = resolveURL(base, f.Link)
+		if link, err := url.Parse(f.Link); err == nil && link.IsAbs() {
+			base = link
+		}
+	}
+	f.base = ""
+
+	for i := range f.Entries {
+		e := &f.Entries[i]
+		entryBase := base
+		if e.base != "" {
+			entryBase = resolveReference(base, e.base)
+			e.base = ""
+		}
+		if entryBase == nil {
+			continue
+		}
+
+		e.Link = resolveURL(entryBase, e.Link)
+		e.CommentsURL = resolveURL(entryBase, e.CommentsURL)
+		e.Image = resolveURL(entryBase, e.Image)
+		for j := range e.Enclosures {
+			e.Enclosures[j].URL = resolveURL(entryBase, e.Enclosures[j].URL)
+		}
+		e.Description = resolveHTML(entryBase, e.Description)
+		e.Content = resolveHTML(entryBase, e.Content)
+	}
+}
+
+// resolveReference resolves ref against base, which may be nil
+func resolveReference(base *url.URL, ref string) *url.URL {
+	u, err := url.Parse(strings.TrimSpace(ref))
+	if err != nil {
+		return base
+	}
+	if base == nil {
+		return u
+	}
+	return base.ResolveReference(u)
+}
+
+// resolveURL returns ref resolved against base. Absolute and unparsable
+// references are returned unchanged.
+func resolveURL(base *url.URL, ref string) string {
+	trimmed := strings.TrimSpace(ref)
+	if base == nil || trimmed == "" {
+		return ref
+	}
+	u, err := url.Parse(trimmed)
+	if err != nil || u.IsAbs() {
+		return ref
+	}
+	return base.ResolveReference(u).String()
+}
+
+// urlAttributes are the HTML attributes holding a URL that are resolved in content
+var urlAttributes = map[string]bool{
+	"href":   true,
+	"src":    true,
+	"poster": true,
+}
+
+// resolveHTML resolves the URLs in the links, images and media of an HTML
+// fragment. Markup that needs no change is kept as it was.
+func resolveHTML(base *url.URL, content string) string {
+	if !strings.Contains(content, "<") {
+		return content
+	}
+
+	var b strings.Builder
+	changed := false
+	tokenizer := html.NewTokenizer(strings.NewReader(content))
+	for {
+		tokenType := tokenizer.Next()
+		if tokenType == html.ErrorToken {
+			break
+		}
+		raw := string(tokenizer.Raw())
+		if tokenType != html.StartTagToken && tokenType != html.SelfClosingTagToken {
+			b.WriteString(raw)
+			continue
+		}
+
+		token := tokenizer.Token()
+		rewritten := false
+		for i, a := range token.Attr {
+			value := a.Val
+			switch {
+			case urlAttributes[a.Key]:
+				value = resolveURL(base, a.Val)
+			case a.Key == "srcset":
+				value = resolveSrcset(base, a.Val)
+			}
+			if value != a.Val {
+				token.Attr[i].Val = value
+				rewritten = true
+			}
+		}
+		if rewritten {
+			b.WriteString(token.String())
+			changed = true
+		} else {
+			b.WriteString(raw)
+		}
+	}
+
+	if !changed {
+		return content
+	}
+	return b.String()
+}
+
+// resolveSrcset resolves the image URLs of a srcset attribute
+func resolveSrcset(base *url.URL, srcset string) string {
+	candidates := strings.Split(srcset, ",")
+	changed := false
+	for i, candidate := range candidates {
+		fields := strings.Fields(candidate)
+		if len(fields) == 0 {
+			continue
+		}
+		if resolved := resolveURL(base, fields[0]); resolved != fields[0] {
+			fields[0] = resolved
+			candidates[i] = strings.Join(fields, " ")
+			changed = true
+		}
+	}
+	if !changed {
+		return srcset
+	}
+	return strings.Join(candidates, ",")
+}
@@ -0,0 +1,173 @@
+package rss
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RetryPolicy controls how a failed feed request is retried within one fetch
+type RetryPolicy struct {
+	MaxRetries int           // Retries after the first attempt
+	BaseDelay  time.Duration // Delay before the first retry, doubled for each further retry
+	MaxDelay   time.Duration // Upper bound for a single delay, including Retry-After
+}
+
+// DefaultRetryPolicy retries transient failures twice within a few seconds
+var DefaultRetryPolicy = RetryPolicy{
+	MaxRetries: 2,
+	BaseDelay:  500 * time.Millisecond,
+	MaxDelay:   10 * time.Second,
+}
+
+// Backoff between fetches of a persistently failing feed. The first failure
+// does not delay the next fetch; every further consecutive failure doubles
+// the delay, up to maxFeedBackoff.
+const (
+	baseFeedBackoff = 5 * time.Minute
+	maxFeedBackoff  = 2 * time.Hour
+)
+
+// SetRetryPolicy changes how failed requests are retried
+func (c *Client) SetRetryPolicy(policy RetryPolicy) {
+	c.retry = policy
+}
+
+// retryable reports whether a failed request may succeed when tried again
+func retryable(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	if errors.Is(err, ErrBodyTooLarge) || errors.Is(err, errContentEncoding) {
+		return false
+	}
+	var httpErr *HTTPError
+	if errors.As(err, &httpErr) {
+		return httpErr.StatusCode == http.StatusTooManyRequests || httpErr.StatusCode >= 500
+	}
+	// Network errors
+	return true
+}
+
+// delay returns how long to wait before retry number attempt (starting at 0).
+// The delay is jittered between half and all of the exponential backoff so
+// that feeds failing together do not retry in lockstep.
+func (p RetryPolicy) delay(attempt int) time.Duration {
+	d := p.BaseDelay << attempt
+	if d <= 0 || d > p.MaxDelay {
+		d = p.MaxDelay
+	}
+	if d <= 0 {
+		return 0
+	}
+	return d/2 + rand.N(d/2+1)
+}
+
+// getWithRetry performs get, retrying transient failures according to the
+// client's retry policy. A Retry-After longer than MaxDelay ends the retries.
+func (c *Client) getWithRetry(ctx context.Context, httpClient *http.Client, url string, header http.Header, cached *CacheEntry) (*response, error) {
+	for attempt := 0; ; attempt++ {
+		resp, err := c.get(ctx, httpClient, url, header, cached)
+		if err == nil || attempt >= c.retry.MaxRetries || !retryable(err) {
+			return resp, err
+		}
+
+		wait := c.retry.delay(attempt)
+		var httpErr *HTTPError
+		if errors.As(err, &httpErr) && httpErr.RetryAfter > 0 {
+			if httpErr.RetryAfter > c.retry.MaxDelay {
+				return nil, err
+			}
+			wait = httpErr.RetryAfter
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// parseRetryAfter parses a Retry-After header given in seconds or as an HTTP date
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return max(0, time.Duration(seconds)*time.Second)
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		return max(0, t.Sub(now))
+	}
+	return 0
+}
+
+// feedFailure tracks consecutive failed fetches of a feed
+type feedFailure struct {
+	count       int
+	lastErr     error
+	nextAttempt time.Time
+}
+
+// failureTracker records failing feeds so that they are fetched less often.
+// It is safe for concurrent use.
+type failureTracker struct {
+	mu    sync.Mutex
+	feeds map[string]*feedFailure
+}
+
+// backoff returns the failure state of a feed if it should not be fetched yet
+func (t *failureTracker) backoff(url string, now time.Time) (feedFailure, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	failure, ok := t.feeds[url]
+	if !ok || !now.Before(failure.nextAttempt) {
+		return feedFailure{}, false
+	}
+	return *failure, true
+}
+
+// record updates a feed's failure state after a fetch and returns it
+func (t *failureTracker) record(url string, err error, now time.Time) feedFailure {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if err == nil {
+		delete(t.feeds, url)
+		return feedFailure{}
+	}
+	if t.feeds == nil {
+		t.feeds = make(map[string]*feedFailure)
+	}
+	failure, ok := t.feeds[url]
+	if !ok {
+		failure = &feedFailure{}
+		t.feeds[url] = failure
+	}
+	failure.count++
+	failure.lastErr = err
+
+	var wait time.Duration
+	if failure.count > 1 {
+		wait = baseFeedBackoff << (failure.count - 2)
+		if wait <= 0 || wait > maxFeedBackoff {
+			wait = maxFeedBackoff
+		}
+	}
+	var httpErr *HTTPError
+	if errors.As(err, &httpErr) && httpErr.RetryAfter > wait {
+		wait = httpErr.RetryAfter
+	}
+	failure.nextAttempt = now.Add(wait)
+	return *failure
+}
@@ -0,0 +1,90 @@
+package rss
+
+import (
+	"bytes"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// isRSS reports whether the document is an RSS 2.0 feed
+func isRSS(contentType string, body []byte) bool {
+	return hasRootElement(body, "rss")
+}
+
+// parseRSS parses an RSS 2.0 document into a Feed
+func parseRSS(body []byte) (*Feed, error) {
+	return decodeRSS(bytes.NewReader(body))
+}
+
+// decodeRSS parses an RSS 2.0 document read from r into a Feed
+func decodeRSS(r io.Reader) (*Feed, error) {
+	var rss RSS
+	if err := decodeXML(r, &rss); err != nil {
+		return nil, err
+	}
+	return rss.toFeed(), nil
+}
+
+// toFeed converts the RSS document into the format-neutral Feed model
+func (r *RSS) toFeed() *Feed {
+	feed := &Feed{
+		Title:       r.Channel.Title,
+		Link:        r.Channel.Link,
+		Description: r.Channel.Description,
+		base:        r.Channel.Base,
+	}
+
+	for _, item := range r.Channel.Items {
+		// dc:creator usually holds a display name, while author holds an email address
+		author := item.Creator
+		if author == "" {
+			author = item.Author
+		}
+
+		// A permalink GUID doubles as the item link when none is given
+		link := item.Link
+		if link == "" && item.GUID.IsPermaLink != "false" {
+			link = strings.TrimSpace(item.GUID.Value)
+		}
+
+		media := item.MediaContent
+		for _, group := range item.MediaGroups {
+			media = append(media, group.Contents...)
+		}
+
+		duration := parseDuration(item.ITunesDuration)
+		if duration == 0 {
+			duration = mediaDuration(media)
+		}
+
+		// Invalid numbers must not fail the whole feed
+		commentCount, _ := strconv.Atoi(strings.TrimSpace(item.CommentCount))
+		episode, _ := strconv.Atoi(strings.TrimSpace(item.ITunesEpisode))
+
+		image := item.ITunesImage.Href
+		if image == "" {
+			image = r.Channel.ITunesImage.Href
+		}
+
+		feed.Entries = append(feed.Entries, Entry{
+			ID:           entryID(item.GUID.Value, link, item.Title, item.PubDate),
+			Title:        item.Title,
+			Link:         link,
+			Description:  item.Description,
+			PubDate:      entryDate(item.PubDate),
+			Content:      item.Content,
+			Author:       author,
+			Categories:   item.Categories,
+			Enclosures:   mergeEnclosures(item.Enclosures, media),
+			CommentsURL:  item.Comments,
+			CommentCount: commentCount,
+			Duration:     duration,
+			Episode:      episode,
+			Image:        image,
+			base:         item.Base,
+		})
+	}
+
+	return feed
+}
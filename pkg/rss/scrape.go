@@ -0,0 +1,219 @@
+package rss
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/andybalholm/cascadia"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+	"golang.org/x/net/html/charset"
+)
+
+// ScrapeFormat is the Format of feeds synthesized from web pages
+const ScrapeFormat = "scrape"
+
+// ScrapeOptions builds a feed from a web page that has none. Item selects
+// the element of each article; the other selectors are matched within it.
+// A selector may end in @attr to use an attribute instead of the text, e.g.
+// "time@datetime". Relative links are resolved against the page URL.
+type ScrapeOptions struct {
+	Item    string `json:"item"`
+	Title   string `json:"title,omitempty"`   // Defaults to the item's text
+	Link    string `json:"link,omitempty"`    // Defaults to the item's or its first link's href
+	Date    string `json:"date,omitempty"`    // Uses a datetime attribute when there is one
+	Summary string `json:"summary,omitempty"` // Text of the matched element
+}
+
+// attrSuffix matches the @attr suffix of a field selector
+var attrSuffix = regexp.MustCompile(`@([A-Za-z_][A-Za-z0-9_:.-]*)$`)
+
+// scraper holds the compiled selectors of ScrapeOptions
+type scraper struct {
+	item                       cascadia.Sel
+	title, link, date, summary field
+}
+
+// field is a selector relative to an item, and the attribute to read
+type field struct {
+	sel  cascadia.Sel // nil selects the item itself
+	attr string
+}
+
+// newScraper compiles the selectors of the options
+func newScraper(opts *ScrapeOptions) (*scraper, error) {
+	if strings.TrimSpace(opts.Item) == "" {
+		return nil, fmt.Errorf("scrape: missing item selector")
+	}
+	item, err := cascadia.Parse(opts.Item)
+	if err != nil {
+		return nil, fmt.Errorf("scrape: invalid item selector %q: %w", opts.Item, err)
+	}
+
+	s := &scraper{item: item}
+	for _, f := range []struct {
+		name, selector string
+		field          *field
+	}{
+		{"title", opts.Title, &s.title},
+		{"link", opts.Link, &s.link},
+		{"date", opts.Date, &s.date},
+		{"summary", opts.Summary, &s.summary},
+	} {
+		if f.selector == "" {
+			continue
+		}
+		selector := f.selector
+		if m := attrSuffix.FindStringSubmatchIndex(selector); m != nil {
+			f.field.attr = selector[m[2]:m[3]]
+			selector = selector[:m[0]]
+		}
+		if selector = strings.TrimSpace(selector); selector == "" {
+			continue
+		}
+		if f.field.sel, err = cascadia.Parse(selector); err != nil {
+			return nil, fmt.Errorf("scrape: invalid %s selector %q: %w", f.name, f.selector, err)
+		}
+	}
+	return s, nil
+}
+
+// scrape parses an HTML page read from r and builds a feed from the elements
+// matched by the options
+func scrape(opts *ScrapeOptions, pageURL, contentType string, r io.Reader) (*Feed, error) {
+	s, err := newScraper(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	r, err = charset.NewReader(r, contentType)
+	if err != nil {
+		return nil, fmt.Errorf("scrape: %w", err)
+	}
+	doc, err := html.Parse(r)
+	if err != nil {
+		return nil, fmt.Errorf("scrape: failed to parse page: %w", err)
+	}
+
+	base, err := url.Parse(pageURL)
+	if err != nil {
+		return nil, fmt.Errorf("scrape: invalid page URL: %w", err)
+	}
+	if node := cascadia.Query(doc, cascadia.MustCompile("base[href]")); node != nil {
+		if resolved, err := base.Parse(strings.TrimSpace(attribute(node, "href"))); err == nil {
+			base = resolved
+		}
+	}
+
+	feed := &Feed{Link: pageURL, Format: ScrapeFormat}
+	if node := cascadia.Query(doc, cascadia.MustCompile("title")); node != nil {
+		feed.Title = nodeText(node)
+	}
+
+	items := cascadia.QueryAll(doc, s.item)
+	if len(items) == 0 {
+		return nil, fmt.Errorf("scrape: no elements match item selector %q", opts.Item)
+	}
+	for _, item := range items {
+		title := s.title.value(item, false)
+		link := s.linkValue(item)
+		if link != "" {
+			if resolved, err := base.Parse(link); err == nil {
+				link = resolved.String()
+			}
+		}
+		var date, summary string
+		if s.date.set() {
+			date = s.date.value(item, true)
+		}
+		if s.summary.set() {
+			summary = s.summary.value(item, false)
+		}
+
+		feed.Entries = append(feed.Entries, Entry{
+			ID:          entryID("", link, title, date),
+			Title:       title,
+			Link:        link,
+			Description: summary,
+			PubDate:     entryDate(date),
+		})
+	}
+	return feed, nil
+}
+
+// linkValue returns the unresolved link of an item. Without a link selector
+// the item's own href is used, or that of the first link inside it.
+func (s *scraper) linkValue(item *html.Node) string {
+	if s.link.set() {
+		f := s.link
+		if f.attr == "" {
+			f.attr = "href"
+		}
+		return f.value(item, false)
+	}
+	if item.DataAtom == atom.A {
+		return strings.TrimSpace(attribute(item, "href"))
+	}
+	if a := cascadia.Query(item, cascadia.MustCompile("a[href]")); a != nil {
+		return strings.TrimSpace(attribute(a, "href"))
+	}
+	return ""
+}
+
+// value returns the field's attribute or text within an item. With
+// preferDatetime, a datetime attribute is used when no attribute is given.
+func (f field) value(item *html.Node, preferDatetime bool) string {
+	node := item
+	if f.sel != nil {
+		if node = cascadia.Query(item, f.sel); node == nil {
+			return ""
+		}
+	}
+	if f.attr != "" {
+		return strings.TrimSpace(attribute(node, f.attr))
+	}
+	if preferDatetime {
+		if datetime := strings.TrimSpace(attribute(node, "datetime")); datetime != "" {
+			return datetime
+		}
+	}
+	return nodeText(node)
+}
+
+// set reports whether the field was configured
+func (f field) set() bool {
+	return f.sel != nil || f.attr != ""
+}
+
+// attribute returns the value of a node's attribute
+func attribute(node *html.Node, name string) string {
+	for _, a := range node.Attr {
+		if a.Namespace == "" && strings.EqualFold(a.Key, name) {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+// nodeText returns the text of a node and its descendants with whitespace
+// collapsed, skipping scripts and styles
+func nodeText(node *html.Node) string {
+	var b strings.Builder
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		switch {
+		case n.Type == html.TextNode:
+			b.WriteString(n.Data)
+		case n.Type == html.ElementNode && (n.DataAtom == atom.Script || n.DataAtom == atom.Style):
+			return
+		}
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			walk(child)
+		}
+	}
+	walk(node)
+	return strings.Join(strings.Fields(b.String()), " ")
+}
@@ -0,0 +1,169 @@
+package rss
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Prefixes of feed URLs that are read locally instead of over HTTP
+const (
+	FilePrefix = "file://"
+	ExecPrefix = "exec:"
+)
+
+// DefaultCommandTimeout limits how long an exec: source or filter may run
+const DefaultCommandTimeout = time.Minute
+
+// SetCommandTimeout limits how long an exec: source or a filter may run.
+// Values below 1 select DefaultCommandTimeout.
+func (c *Client) SetCommandTimeout(timeout time.Duration) {
+	if timeout <= 0 {
+		timeout = DefaultCommandTimeout
+	}
+	c.commandTimeout = timeout
+}
+
+// IsLocalSource reports whether a feed URL names a local file or command
+func IsLocalSource(feedURL string) bool {
+	return strings.HasPrefix(feedURL, FilePrefix) || strings.HasPrefix(feedURL, ExecPrefix)
+}
+
+// fetchLocal reads a file:// feed or runs an exec: command and parses its
+// output, piped through the feed's filter if it has one
+func (c *Client) fetchLocal(ctx context.Context, info FeedInfo) (*Feed, error) {
+	if command, ok := strings.CutPrefix(info.URL, ExecPrefix); ok {
+		if strings.TrimSpace(command) == "" {
+			return nil, fmt.Errorf("invalid exec source: missing command")
+		}
+		var feed *Feed
+		err := c.runCommand(ctx, command, nil, func(output io.Reader) (err error) {
+			feed, _, err = c.parseBody(ctx, info, info.URL, "", output)
+			return err
+		})
+		return feed, err
+	}
+
+	path, err := filePath(info.URL)
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open feed file: %w", err)
+	}
+	defer file.Close()
+
+	body := &limitedBody{r: file, remaining: c.maxBodySize, limit: c.maxBodySize}
+	feed, _, err := c.parseBody(ctx, info, info.URL, mime.TypeByExtension(filepath.Ext(path)), body)
+	return feed, err
+}
+
+// parseBody parses a feed document fetched from docURL, first piping it
+// through the feed's filter if it has one, and scraping it if it is a web
+// page. Like parseStream it returns the start of an unfiltered feed document.
+func (c *Client) parseBody(ctx context.Context, info FeedInfo, docURL, contentType string, body io.Reader) (*Feed, []byte, error) {
+	parse := parseStream
+	if info.Scrape != nil {
+		parse = func(contentType string, r io.Reader) (*Feed, []byte, error) {
+			feed, err := scrape(info.Scrape, docURL, contentType, r)
+			return feed, nil, err
+		}
+	}
+	if info.Filter == "" {
+		return parse(contentType, body)
+	}
+
+	var feed *Feed
+	err := c.runCommand(ctx, info.Filter, body, func(output io.Reader) (err error) {
+		// The filter may convert the document to another format
+		feed, _, err = parse("", output)
+		return err
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("filter: %w", err)
+	}
+	return feed, nil, nil
+}
+
+// filePath returns the local path of a file:// URL. Percent-escapes are
+// decoded and a leading ~/ refers to the home directory.
+func filePath(feedURL string) (string, error) {
+	path, err := url.PathUnescape(strings.TrimPrefix(feedURL, FilePrefix))
+	if err != nil {
+		return "", fmt.Errorf("invalid file URL: %w", err)
+	}
+	if path == "" {
+		return "", fmt.Errorf("invalid file URL: missing path")
+	}
+	if rest, ok := strings.CutPrefix(path, "~/"); ok {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		path = filepath.Join(home, rest)
+	}
+	return path, nil
+}
+
+// runCommand runs a shell command with the given standard input and passes
+// its standard output, limited to the max body size, to read. The command is
+// killed when ctx is done or the command timeout passes.
+func (c *Client) runCommand(ctx context.Context, command string, stdin io.Reader, read func(io.Reader) error) error {
+	cmdCtx, cancel := context.WithTimeout(ctx, c.commandTimeout)
+	defer cancel()
+
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(cmdCtx, "sh", "-c", command)
+	cmd.Stdin = stdin
+	cmd.Stderr = &stderr
+	cmd.WaitDelay = time.Second
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to run command: %w", err)
+	}
+	// Processes started by the command may keep its output open after it
+	// is killed, so reads are interrupted and Wait gives up after WaitDelay
+	stop := context.AfterFunc(cmdCtx, func() { stdout.Close() })
+	defer stop()
+
+	readErr := read(&limitedBody{r: stdout, remaining: c.maxBodySize, limit: c.maxBodySize})
+	tooLarge := errors.Is(readErr, ErrBodyTooLarge)
+	if tooLarge {
+		// Stop the command rather than waiting for output nobody reads
+		cancel()
+	} else {
+		io.Copy(io.Discard, stdout)
+	}
+	waitErr := cmd.Wait()
+
+	switch {
+	case ctx.Err() != nil:
+		return ctx.Err()
+	case errors.Is(cmdCtx.Err(), context.DeadlineExceeded):
+		return fmt.Errorf("command timed out after %v", c.commandTimeout)
+	case waitErr != nil && !tooLarge:
+		return commandError(waitErr, &stderr)
+	}
+	return readErr
+}
+
+// commandError describes a failed command, including what it wrote to stderr
+func commandError(err error, stderr *bytes.Buffer) error {
+	if msg := strings.TrimSpace(stderr.String()); msg != "" {
+		return fmt.Errorf("command failed: %w: %s", err, msg)
+	}
+	return fmt.Errorf("command failed: %w", err)
+}
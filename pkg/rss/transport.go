@@ -0,0 +1,255 @@
+package rss
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// Setting Proxy to ProxyDirect connects directly, ignoring the proxy environment variables
+const ProxyDirect = "direct"
+
+// TransportOptions configures how feed servers are reached. Options set on a
+// feed override the client's options field by field.
+type TransportOptions struct {
+	// Proxy is an http://, https:// or socks5:// proxy URL, or ProxyDirect.
+	// When empty the HTTP_PROXY, HTTPS_PROXY and NO_PROXY variables are used.
+	Proxy string `json:"proxy,omitempty"`
+	// NoProxy lists hosts reached without the proxy: "example.com" matches the
+	// domain and its subdomains, "*.example.com" only subdomains, "host:port"
+	// a single port, "10.0.0.0/8" an IP range and "*" every host.
+	NoProxy []string `json:"no_proxy,omitempty"`
+	// CAFile is a PEM bundle of certificate authorities trusted in addition
+	// to the system roots
+	CAFile string `json:"ca_file,omitempty"`
+	// CertFile and KeyFile are a PEM client certificate and its private key
+	CertFile string `json:"cert_file,omitempty"`
+	KeyFile  string `json:"key_file,omitempty"`
+}
+
+// IsZero reports whether no options are set
+func (o TransportOptions) IsZero() bool {
+	return o.Proxy == "" && len(o.NoProxy) == 0 && o.CAFile == "" && o.CertFile == "" && o.KeyFile == ""
+}
+
+// merge returns o with the fields set in override replaced
+func (o TransportOptions) merge(override TransportOptions) TransportOptions {
+	if override.Proxy != "" {
+		o.Proxy = override.Proxy
+	}
+	if len(override.NoProxy) > 0 {
+		o.NoProxy = override.NoProxy
+	}
+	if override.CAFile != "" {
+		o.CAFile = override.CAFile
+	}
+	if override.CertFile != "" || override.KeyFile != "" {
+		o.CertFile = override.CertFile
+		o.KeyFile = override.KeyFile
+	}
+	return o
+}
+
+// SetTransport configures the proxy and TLS settings used for all feeds that
+// do not override them
+func (c *Client) SetTransport(options TransportOptions) error {
+	transport, err := newTransport(options)
+	if err != nil {
+		return err
+	}
+
+	c.transportMu.Lock()
+	defer c.transportMu.Unlock()
+
+	c.transport = options
+	c.httpClient.Transport = transport
+	c.feedClients = nil
+	return nil
+}
+
+// Transport returns the HTTP transport built from the client's transport
+// options, for other downloads that should reach servers the same way
+func (c *Client) Transport() http.RoundTripper {
+	c.transportMu.Lock()
+	defer c.transportMu.Unlock()
+
+	if c.httpClient.Transport == nil {
+		return http.DefaultTransport
+	}
+	return c.httpClient.Transport
+}
+
+// clientFor returns the HTTP client for a feed, building and caching one for
+// feeds with their own transport options
+func (c *Client) clientFor(info FeedInfo) (*http.Client, error) {
+	if info.Transport == nil || info.Transport.IsZero() {
+		return c.httpClient, nil
+	}
+
+	c.transportMu.Lock()
+	defer c.transportMu.Unlock()
+
+	options := c.transport.merge(*info.Transport)
+	key, err := json.Marshal(options)
+	if err != nil {
+		return nil, err
+	}
+	if client, ok := c.feedClients[string(key)]; ok {
+		return client, nil
+	}
+
+	transport, err := newTransport(options)
+	if err != nil {
+		return nil, err
+	}
+	client := &http.Client{Timeout: c.httpClient.Timeout, Transport: transport}
+	if c.feedClients == nil {
+		c.feedClients = make(map[string]*http.Client)
+	}
+	c.feedClients[string(key)] = client
+	return client, nil
+}
+
+// newTransport builds an HTTP transport from the options
+func newTransport(options TransportOptions) (*http.Transport, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+
+	proxy, err := options.proxyFunc()
+	if err != nil {
+		return nil, err
+	}
+	transport.Proxy = proxy
+
+	tlsConfig, err := options.tlsConfig()
+	if err != nil {
+		return nil, err
+	}
+	if tlsConfig != nil {
+		transport.TLSClientConfig = tlsConfig
+	}
+	return transport, nil
+}
+
+// proxyFunc returns the proxy selection function for http.Transport
+func (o TransportOptions) proxyFunc() (func(*http.Request) (*url.URL, error), error) {
+	var proxy func(*http.Request) (*url.URL, error)
+	switch strings.ToLower(strings.TrimSpace(o.Proxy)) {
+	case "":
+		proxy = http.ProxyFromEnvironment
+	case ProxyDirect:
+		return nil, nil
+	default:
+		proxyURL, err := url.Parse(o.Proxy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy URL: %w", err)
+		}
+		switch proxyURL.Scheme {
+		case "http", "https", "socks5", "socks5h":
+		default:
+			return nil, fmt.Errorf("unsupported proxy scheme %q", proxyURL.Scheme)
+		}
+		proxy = http.ProxyURL(proxyURL)
+	}
+
+	if len(o.NoProxy) == 0 {
+		return proxy, nil
+	}
+	noProxy := o.NoProxy
+	return func(req *http.Request) (*url.URL, error) {
+		if bypassProxy(req.URL, noProxy) {
+			return nil, nil
+		}
+		return proxy(req)
+	}, nil
+}
+
+// bypassProxy reports whether a URL matches one of the NoProxy patterns
+func bypassProxy(u *url.URL, patterns []string) bool {
+	host := strings.ToLower(u.Hostname())
+	port := u.Port()
+	if port == "" {
+		port = map[string]string{"http": "80", "https": "443"}[u.Scheme]
+	}
+	ip := net.ParseIP(host)
+
+	for _, pattern := range patterns {
+		pattern = strings.ToLower(strings.TrimSpace(pattern))
+		switch {
+		case pattern == "":
+			continue
+		case pattern == "*":
+			return true
+		case strings.Contains(pattern, "/"):
+			if _, network, err := net.ParseCIDR(pattern); err == nil && ip != nil && network.Contains(ip) {
+				return true
+			}
+			continue
+		}
+
+		if patternHost, patternPort, err := net.SplitHostPort(pattern); err == nil {
+			if patternPort != port {
+				continue
+			}
+			pattern = patternHost
+		}
+
+		switch {
+		case strings.HasPrefix(pattern, "*."):
+			if strings.HasSuffix(host, pattern[1:]) {
+				return true
+			}
+		case strings.HasPrefix(pattern, "."):
+			if host == pattern[1:] || strings.HasSuffix(host, pattern) {
+				return true
+			}
+		default:
+			if host == pattern || strings.HasSuffix(host, "."+pattern) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// tlsConfig returns the TLS configuration for the options, or nil if the
+// defaults apply
+func (o TransportOptions) tlsConfig() (*tls.Config, error) {
+	if o.CAFile == "" && o.CertFile == "" && o.KeyFile == "" {
+		return nil, nil
+	}
+	config := &tls.Config{}
+
+	if o.CAFile != "" {
+		pem, err := os.ReadFile(o.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA file: %w", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in CA file %s", o.CAFile)
+		}
+		config.RootCAs = pool
+	}
+
+	if o.CertFile != "" || o.KeyFile != "" {
+		if o.CertFile == "" || o.KeyFile == "" {
+			return nil, fmt.Errorf("client certificates need both cert_file and key_file")
+		}
+		cert, err := tls.LoadX509KeyPair(o.CertFile, o.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %w", err)
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+
+	return config, nil
+}
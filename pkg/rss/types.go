@@ -2,6 +2,8 @@ package rss
 
 import (
 	"encoding/xml"
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -13,25 +15,125 @@ type RSS struct {
 
 // Channel represents an RSS channel
 type Channel struct {
+	Base        string `xml:"http://www.w3.org/XML/1998/namespace base,attr"`
 	Title       string `xml:"title"`
 	Link        string `xml:"link"`
 	Description string `xml:"description"`
 	Items       []Item `xml:"item"`
+
+	ITunesImage ITunesImage `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd image"`
 }
 
 // Item represents an RSS item/article
 type Item struct {
-	Title       string `xml:"title"`
-	Link        string `xml:"link"`
-	Description string `xml:"description"`
-	PubDate     string `xml:"pubDate"`
+	Base        string      `xml:"http://www.w3.org/XML/1998/namespace base,attr"`
+	Title       string      `xml:"title"`
+	Link        string      `xml:"link"`
+	Description string      `xml:"description"`
+	PubDate     string      `xml:"pubDate"`
+	GUID        GUID        `xml:"guid"`
+	Author      string      `xml:"author"`
+	Categories  []string    `xml:"category"`
+	Enclosures  []Enclosure `xml:"enclosure"`
+
+	// Common namespace extensions
+	Content      string `xml:"http://purl.org/rss/1.0/modules/content/ encoded"`
+	Creator      string `xml:"http://purl.org/dc/elements/1.1/ creator"`
+	CommentCount string `xml:"http://purl.org/rss/1.0/modules/slash/ comments"`
+	// Comments must follow CommentCount so that slash:comments is not matched here
+	Comments string `xml:"comments"`
+
+	// Podcast and media extensions
+	MediaContent   []MediaContent `xml:"http://search.yahoo.com/mrss/ content"`
+	MediaGroups    []MediaGroup   `xml:"http://search.yahoo.com/mrss/ group"`
+	ITunesDuration string         `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd duration"`
+	ITunesEpisode  string         `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd episode"`
+	ITunesImage    ITunesImage    `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd image"`
+}
+
+// GUID represents an RSS item's globally unique identifier
+type GUID struct {
+	Value       string `xml:",chardata"`
+	IsPermaLink string `xml:"isPermaLink,attr"`
+}
+
+// Enclosure represents a media file attached to an item
+type Enclosure struct {
+	URL    string `xml:"url,attr"`
+	Type   string `xml:"type,attr"`
+	Length int64  `xml:"length,attr"`
+}
+
+// UnmarshalXML decodes an RSS enclosure, tolerating lengths such as
+// "unknown" that would otherwise fail the whole feed
+func (e *Enclosure) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	var raw struct {
+		URL    string `xml:"url,attr"`
+		Type   string `xml:"type,attr"`
+		Length string `xml:"length,attr"`
+	}
+	if err := d.DecodeElement(&raw, &start); err != nil {
+		return err
+	}
+	*e = Enclosure{URL: raw.URL, Type: raw.Type, Length: parseLength(raw.Length)}
+	return nil
+}
+
+// parseLength parses a size in bytes, returning 0 when it is missing or not
+// a whole number
+func parseLength(value string) int64 {
+	n, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+	if err != nil || n < 0 {
+		return 0
+	}
+	return n
 }
 
-// Article represents a processed RSS article with parsed date
+// Article represents an entry together with the name of the feed it came from
 type Article struct {
-	Title       string
-	Link        string
-	Description string
-	PubDate     time.Time
-	FeedName    string
+	ID           string
+	Title        string
+	Link         string
+	Description  string
+	PubDate      time.Time
+	Content      string
+	Author       string
+	Categories   []string
+	Enclosures   []Enclosure
+	CommentsURL  string
+	CommentCount int
+	Duration     time.Duration
+	Episode      int
+	Image        string
+	FeedName     string
+	FeedURL      string
+	Undated      bool // PubDate is when the article was first seen, as its feed gives no usable date
+}
+
+// newArticle creates an article from an entry of the given feed
+func newArticle(entry Entry, feed FeedInfo) Article {
+	return Article{
+		ID:           entry.ID,
+		Title:        entry.Title,
+		Link:         entry.Link,
+		Description:  entry.Description,
+		PubDate:      entry.PubDate,
+		Content:      entry.Content,
+		Author:       entry.Author,
+		Categories:   entry.Categories,
+		Enclosures:   entry.Enclosures,
+		CommentsURL:  entry.CommentsURL,
+		CommentCount: entry.CommentCount,
+		Duration:     entry.Duration,
+		Episode:      entry.Episode,
+		Image:        entry.Image,
+		FeedName:     feed.Name,
+		FeedURL:      feed.URL,
+	}
+}
+
+// Key identifies the article across all feeds. IDs are only unique within a
+// feed, as they may be short GUIDs such as "1" or relative links.
+func (a Article) Key() string {
+	return a.FeedURL + "\n" + a.ID
 }
\ No newline at end of file
@@ -1,19 +1,42 @@
 package tui
 
 import (
+	"context"
+	"fmt"
 	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"rsss/pkg/browser"
 	"rsss/pkg/config"
+	"rsss/pkg/download"
 	"rsss/pkg/rss"
 )
 
-// FetchAllFeedsCmd fetches all configured RSS feeds
-func FetchAllFeedsCmd(client *rss.Client, feeds *config.FeedConfig) tea.Cmd {
+// FetchAllFeedsCmd fetches all configured RSS feeds until ctx is cancelled.
+// The generation is passed back in FetchMsg to recognize superseded fetches.
+func FetchAllFeedsCmd(ctx context.Context, client *rss.Client, feeds *config.FeedConfig, generation int) tea.Cmd {
 	return tea.Cmd(func() tea.Msg {
-		articles, err := client.FetchMultipleFeeds(feeds.Feeds)
-		return FetchMsg{Articles: articles, Err: err}
+		articles, results, err := client.FetchMultipleFeedsContext(ctx, feeds.Feeds)
+		return FetchMsg{Generation: generation, Articles: articles, Results: results, Err: err}
+	})
+}
+
+// DiscoverFeedsCmd checks whether a URL is a feed or a page advertising feeds
+func DiscoverFeedsCmd(client *rss.Client, name, url string) tea.Cmd {
+	return tea.Cmd(func() tea.Msg {
+		feeds, err := client.Discover(url)
+		if err == nil && len(feeds) == 0 {
+			err = fmt.Errorf("no feeds found at %s", url)
+		}
+		return DiscoverMsg{Name: name, URL: url, Feeds: feeds, Err: err}
+	})
+}
+
+// UnlockCmd unlocks the credential store with a passphrase. Deriving the key
+// is deliberately slow, so it runs outside the update loop.
+func UnlockCmd(store *config.CredentialStore, passphrase string) tea.Cmd {
+	return tea.Cmd(func() tea.Msg {
+		return UnlockMsg{Err: store.Unlock(passphrase)}
 	})
 }
 
@@ -30,4 +53,30 @@ func OpenURLCmd(url string) tea.Cmd {
 		err := browser.Open(url)
 		return OpenURLMsg{URL: url, Err: err}
 	})
+}
+
+// PlayMediaCmd opens a media URL or file with the player configured for its MIME type
+func PlayMediaCmd(players map[string]string, mimeType, target string) tea.Cmd {
+	return tea.Cmd(func() tea.Msg {
+		err := browser.Play(players, mimeType, target)
+		return PlayMediaMsg{Target: target, Err: err}
+	})
+}
+
+// DownloadCmd queues an enclosure for download into a per-feed directory
+func DownloadCmd(manager *download.Manager, url, feedName string) tea.Cmd {
+	return tea.Cmd(func() tea.Msg {
+		path, err := manager.Enqueue(url, feedName)
+		return DownloadMsg{
+			Progress: download.Progress{URL: url, Path: path, Status: download.StatusQueued, Total: -1},
+			Err:      err,
+		}
+	})
+}
+
+// WaitForDownloadCmd waits for the next download progress update
+func WaitForDownloadCmd(manager *download.Manager) tea.Cmd {
+	return tea.Cmd(func() tea.Msg {
+		return DownloadMsg{Progress: <-manager.Updates()}
+	})
 }
\ No newline at end of file
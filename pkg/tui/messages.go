@@ -3,13 +3,29 @@ package tui
 import (
 	"time"
 
+	"rsss/pkg/download"
 	"rsss/pkg/rss"
 )
 
 // FetchMsg represents the result of fetching RSS feeds
 type FetchMsg struct {
-	Articles []rss.Article
-	Err      error
+	Generation int
+	Articles   []rss.Article
+	Results    []rss.FetchResult
+	Err        error
+}
+
+// DiscoverMsg represents the feeds found at a URL entered on the Add Feed screen
+type DiscoverMsg struct {
+	Name  string
+	URL   string
+	Feeds []rss.DiscoveredFeed
+	Err   error
+}
+
+// UnlockMsg represents the result of unlocking the credential store
+type UnlockMsg struct {
+	Err error
 }
 
 // TickMsg represents a timer tick for auto-refresh
@@ -25,4 +41,16 @@ type SaveMsg struct {
 type OpenURLMsg struct {
 	URL string
 	Err error
+}
+
+// PlayMediaMsg represents the result of launching a media player
+type PlayMediaMsg struct {
+	Target string
+	Err    error
+}
+
+// DownloadMsg represents a change in the state of an enclosure download
+type DownloadMsg struct {
+	Progress download.Progress
+	Err      error
 }
\ No newline at end of file
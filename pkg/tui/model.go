@@ -1,11 +1,15 @@
 package tui
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"strings"
 	"time"
 
+	tea "github.com/charmbracelet/bubbletea"
 	"rsss/pkg/config"
+	"rsss/pkg/download"
 	"rsss/pkg/rss"
 )
 
@@ -20,6 +24,8 @@ const (
 	StateConfigure
 	StateAddFeed
 	StateRemoveFeed
+	StateChooseFeed
+	StateUnlock
 )
 
 // Model represents the TUI application model
@@ -36,12 +42,29 @@ type Model struct {
 	Input        string
 	LastRefresh  time.Time
 	RSSClient    *rss.Client
+	Credentials  *config.CredentialStore
+	Downloads    *download.Manager
 	Width        int
 	Height       int
 	ViewportTop  int // For scrolling in feed view
+
+	// Feed autodiscovery for the Add Feed screen
+	Discovering     bool                 // Whether the entered URL is being checked
+	Discovered      []rss.DiscoveredFeed // Feeds found on an HTML page to choose from
+	PendingFeedName string               // Name entered for the feed being added
+
+	// Outcome of the last fetch of each feed, keyed by feed URL
+	FeedHealth map[string]rss.FetchResult
+
+	// Unlocking the credential store at startup
+	Unlocking bool // Whether the passphrase is being checked
+
+	// Only the most recent refresh is kept; earlier ones are cancelled
+	FetchGeneration int
+	cancelFetch     context.CancelFunc
 	
 	// Notification system
-	SeenArticles    map[string]bool // Track seen article URLs
+	SeenArticles    map[string]bool // Track seen articles by their key
 	NewArticleCount int             // Count of new articles since last check
 	ShowNotification bool           // Whether to show notification
 	NotificationMsg  string         // Notification message to display
@@ -54,9 +77,28 @@ func NewModel(cfg *config.Config, feeds *config.FeedConfig, rssClient *rss.Clien
 	if seen, err := config.LoadSeenArticles(cfg.SeenArticlesFile); err == nil {
 		seenArticles = seen.Articles
 	}
+
+	// Credentials referenced by feeds; encrypted ones need the passphrase first
+	credentials, credErr := config.LoadCredentialStore(cfg.CredentialsFile)
+	rssClient.SetCredentials(credentials)
+	state := StateMenu
+	if credentials.Locked() {
+		state = StateUnlock
+	}
+
+	// Revalidate feeds fetched in earlier sessions instead of downloading them again.
+	// An unreadable cache is replaced by an empty one that overwrites it on save.
+	var cacheErr error
+	if rssClient.Cache() == nil {
+		cache, err := config.LoadFeedCache(cfg.CacheFile)
+		if err != nil {
+			cacheErr = fmt.Errorf("failed to load feed cache: %w", err)
+		}
+		rssClient.SetCache(cache)
+	}
 	
 	return &Model{
-		State:        StateMenu,
+		State:        state,
 		MenuSelected: 0,
 		Selected:     0,
 		Feeds:        feeds,
@@ -65,6 +107,10 @@ func NewModel(cfg *config.Config, feeds *config.FeedConfig, rssClient *rss.Clien
 		Loading:      true,
 		LastRefresh:  time.Now(),
 		RSSClient:    rssClient,
+		Credentials:  credentials,
+		Err:          errors.Join(credErr, cacheErr),
+		Downloads:    download.NewManager(cfg.DownloadDir, rssClient.Transport()),
+		FeedHealth:   make(map[string]rss.FetchResult),
 		
 		// Initialize notification system with loaded data
 		SeenArticles:     seenArticles,
@@ -81,6 +127,10 @@ func (m *Model) getMaxVisibleArticles() int {
 	}
 	// Reserve space for header and help text (3 lines total)
 	contentHeight := availableHeight - 3
+	if len(m.failingFeeds()) > 0 {
+		// Line listing the feeds that failed to fetch
+		contentHeight--
+	}
 	return max(1, contentHeight-1)
 }
 
@@ -123,7 +173,7 @@ func (m *Model) checkForNewArticles(articles []rss.Article) {
 	if len(m.SeenArticles) == 0 {
 		// First run - mark all current articles as seen without notification
 		for _, article := range articles {
-			m.SeenArticles[article.Link] = true
+			m.SeenArticles[article.Key()] = true
 		}
 		m.saveSeenArticles()
 		return
@@ -131,9 +181,9 @@ func (m *Model) checkForNewArticles(articles []rss.Article) {
 	
 	newCount := 0
 	for _, article := range articles {
-		if !m.SeenArticles[article.Link] {
+		if !m.SeenArticles[article.Key()] {
 			newCount++
-			m.SeenArticles[article.Link] = true
+			m.SeenArticles[article.Key()] = true
 		}
 	}
 	
@@ -161,6 +211,89 @@ func (m *Model) dismissNotification() {
 
 // saveSeenArticles saves the current seen articles to file
 func (m *Model) saveSeenArticles() {
-	seen := &config.SeenArticles{Articles: m.SeenArticles}
+	seen := config.NewSeenArticles(m.SeenArticles)
 	seen.Save(m.Config.SeenArticlesFile)
+}
+
+// saveFeedCache saves the client's feed cache to file
+func (m *Model) saveFeedCache() {
+	if cache := m.RSSClient.Cache(); cache != nil {
+		config.NewFeedCache(cache).Save(m.Config.CacheFile)
+	}
+}
+
+// failingFeeds returns the results of configured feeds that failed or are
+// being skipped because they keep failing
+func (m *Model) failingFeeds() []rss.FetchResult {
+	var failing []rss.FetchResult
+	for _, feed := range m.Feeds.Feeds {
+		if result, ok := m.FeedHealth[feed.URL]; ok && (result.Status == rss.FetchFailed || result.Status == rss.FetchSkipped) {
+			failing = append(failing, result)
+		}
+	}
+	return failing
+}
+
+// refreshFeeds cancels any fetch in flight and starts fetching all feeds again
+func (m *Model) refreshFeeds() tea.Cmd {
+	m.cancelRefresh()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	m.cancelFetch = cancel
+	m.FetchGeneration++
+	return FetchAllFeedsCmd(ctx, m.RSSClient, m.Feeds, m.FetchGeneration)
+}
+
+// cancelRefresh cancels the fetch in flight, if any
+func (m *Model) cancelRefresh() {
+	if m.cancelFetch != nil {
+		m.cancelFetch()
+		m.cancelFetch = nil
+	}
+}
+
+// updateSubscriptions follows permanent redirects and marks feeds that are
+// gone as dead, saving the feeds file and notifying the user of any changes.
+// It returns the results with their feeds updated accordingly.
+func (m *Model) updateSubscriptions(results []rss.FetchResult) []rss.FetchResult {
+	updated := make([]rss.FetchResult, len(results))
+	changed := false
+
+	for i, result := range results {
+		updated[i] = result
+		for j := range m.Feeds.Feeds {
+			feed := &m.Feeds.Feeds[j]
+			if feed.URL != result.Feed.URL {
+				continue
+			}
+			switch {
+			case result.MovedTo != "" && result.MovedTo != feed.URL:
+				m.notify(fmt.Sprintf("↪️ %s moved to %s", feed.Name, result.MovedTo))
+				feed.URL = result.MovedTo
+				changed = true
+			case result.Status == rss.FetchGone && !feed.Dead:
+				m.notify(fmt.Sprintf("☠ %s is gone and will no longer be fetched", feed.Name))
+				feed.Dead = true
+				changed = true
+			}
+			updated[i].Feed = *feed
+		}
+	}
+
+	if changed {
+		if err := m.Feeds.Save(m.Config.FeedsFile); err != nil {
+			m.Err = err
+		}
+	}
+	return updated
+}
+
+// notify shows a message in the notification bar, after any message already shown
+func (m *Model) notify(msg string) {
+	if m.ShowNotification && m.NotificationMsg != "" {
+		m.NotificationMsg += " | " + msg
+	} else {
+		m.NotificationMsg = msg
+	}
+	m.ShowNotification = true
 }
\ No newline at end of file
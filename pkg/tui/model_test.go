@@ -1,15 +1,37 @@
 package tui
 
 import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
 	"time"
+	"unicode/utf8"
 
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/mattn/go-runewidth"
 	"rsss/pkg/config"
 	"rsss/pkg/rss"
 )
 
-func TestNewModel(t *testing.T) {
+// testConfig returns the default configuration with its files in a
+// temporary directory, so that tests never touch the user's own files
+func testConfig(t *testing.T) *config.Config {
+	dir := t.TempDir()
 	cfg := config.DefaultConfig()
+	cfg.FeedsFile = filepath.Join(dir, "feeds.json")
+	cfg.SeenArticlesFile = filepath.Join(dir, "seen.json")
+	cfg.CacheFile = filepath.Join(dir, "cache.json")
+	cfg.CredentialsFile = filepath.Join(dir, "credentials.json")
+	cfg.ConfigFile = filepath.Join(dir, "config.json")
+	cfg.DownloadDir = filepath.Join(dir, "downloads")
+	return cfg
+}
+
+func TestNewModel(t *testing.T) {
+	cfg := testConfig(t)
 	feeds := &config.FeedConfig{
 		Feeds: []rss.FeedInfo{
 			{Name: "Test Feed", URL: "https://example.com/feed.xml"},
@@ -49,7 +71,7 @@ func TestNewModel(t *testing.T) {
 }
 
 func TestGetSelectedArticle(t *testing.T) {
-	cfg := config.DefaultConfig()
+	cfg := testConfig(t)
 	feeds := &config.FeedConfig{}
 	rssClient := rss.NewClient(5 * time.Second)
 	model := NewModel(cfg, feeds, rssClient)
@@ -105,7 +127,7 @@ func TestGetSelectedArticle(t *testing.T) {
 }
 
 func TestStateTransitions(t *testing.T) {
-	cfg := config.DefaultConfig()
+	cfg := testConfig(t)
 	feeds := &config.FeedConfig{}
 	rssClient := rss.NewClient(5 * time.Second)
 	model := NewModel(cfg, feeds, rssClient)
@@ -134,4 +156,308 @@ func TestStateTransitions(t *testing.T) {
 			t.Errorf("State transition from %v to %v failed", tc.from, tc.to)
 		}
 	}
-}
\ No newline at end of file
+}
+
+func TestCheckForNewArticlesUsesID(t *testing.T) {
+	cfg := testConfig(t)
+	model := NewModel(cfg, &config.FeedConfig{}, rss.NewClient(5*time.Second))
+
+	model.checkForNewArticles([]rss.Article{
+		{ID: "guid-1", Link: "https://example.com/shared"},
+	})
+	if model.ShowNotification {
+		t.Error("Expected no notification on first run")
+	}
+
+	// A different item reusing the same link is still new
+	model.checkForNewArticles([]rss.Article{
+		{ID: "guid-1", Link: "https://example.com/shared"},
+		{ID: "guid-2", Link: "https://example.com/shared"},
+	})
+	if model.NewArticleCount != 1 {
+		t.Errorf("Expected 1 new article, got %d", model.NewArticleCount)
+	}
+
+	// The same item with different tracking parameters is not new
+	model.dismissNotification()
+	model.checkForNewArticles([]rss.Article{
+		{ID: "guid-1", Link: "https://example.com/shared?utm_source=feed"},
+	})
+	if model.ShowNotification {
+		t.Error("Expected no notification for an already seen ID")
+	}
+
+	// IDs are only unique within a feed
+	model.checkForNewArticles([]rss.Article{
+		{ID: "guid-1", Link: "https://example.com/shared"},
+		{ID: "guid-1", FeedURL: "https://other.example.com/feed"},
+	})
+	if model.NewArticleCount != 1 {
+		t.Errorf("Expected the same ID in another feed to be new, got %d new articles", model.NewArticleCount)
+	}
+}
+
+func TestDiscoverMsg(t *testing.T) {
+	cfg := testConfig(t)
+	model := NewModel(cfg, &config.FeedConfig{}, rss.NewClient(5*time.Second))
+
+	// Several feeds on a page are offered to choose from
+	model.State = StateAddFeed
+	model.Update(DiscoverMsg{
+		URL: "https://example.com",
+		Feeds: []rss.DiscoveredFeed{
+			{Title: "Posts", URL: "https://example.com/posts.xml"},
+			{Title: "Comments", URL: "https://example.com/comments.xml"},
+		},
+	})
+	if model.State != StateChooseFeed {
+		t.Fatalf("Expected StateChooseFeed, got %v", model.State)
+	}
+
+	model.Update(tea.KeyMsg{Type: tea.KeyDown})
+	model.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if model.State != StateManageFeeds {
+		t.Errorf("Expected StateManageFeeds after choosing, got %v", model.State)
+	}
+	if len(model.Feeds.Feeds) != 1 || model.Feeds.Feeds[0].Name != "Comments" {
+		t.Errorf("Expected chosen feed to be added with its title, got %+v", model.Feeds.Feeds)
+	}
+
+	// A single feed is added directly, keeping the entered name
+	model.State = StateAddFeed
+	model.Update(DiscoverMsg{
+		Name:  "My Blog",
+		URL:   "https://blog.example.com",
+		Feeds: []rss.DiscoveredFeed{{Title: "Blog", URL: "https://blog.example.com/feed"}},
+	})
+	if len(model.Feeds.Feeds) != 2 || !reflect.DeepEqual(model.Feeds.Feeds[1], rss.FeedInfo{Name: "My Blog", URL: "https://blog.example.com/feed"}) {
+		t.Errorf("Expected feed to be added directly, got %+v", model.Feeds.Feeds)
+	}
+}
+
+func TestFetchMsgFeedHealth(t *testing.T) {
+	cfg := testConfig(t)
+	feeds := &config.FeedConfig{Feeds: []rss.FeedInfo{
+		{Name: "Good", URL: "https://good.example.com/feed"},
+		{Name: "Broken", URL: "https://broken.example.com/feed"},
+		{Name: "New", URL: "https://new.example.com/feed"},
+		{Name: "Flaky", URL: "https://flaky.example.com/feed"},
+	}}
+	model := NewModel(cfg, feeds, rss.NewClient(5*time.Second))
+
+	model.Update(FetchMsg{Results: []rss.FetchResult{
+		{Feed: feeds.Feeds[0], Status: rss.FetchOK, HTTPStatus: 200, ItemCount: 3},
+		{Feed: feeds.Feeds[1], Status: rss.FetchFailed, HTTPStatus: 404, Err: &rss.HTTPError{StatusCode: 404}},
+		{Feed: feeds.Feeds[3], Status: rss.FetchSkipped, Failures: 3, NextAttempt: time.Now().Add(time.Hour), Err: &rss.HTTPError{StatusCode: 503}},
+	}})
+
+	failing := model.failingFeeds()
+	if len(failing) != 2 || failing[0].Feed.Name != "Broken" || failing[1].Feed.Name != "Flaky" {
+		t.Fatalf("Expected Broken to be failing, got %+v", failing)
+	}
+
+	model.State = StateManageFeeds
+	model.Selected = 1
+	view := model.viewManageFeeds()
+	for _, want := range []string{"✓ 1. Good", "✗ 2. Broken", "· 3. New", "⏸ 4. Flaky", "HTTP error: 404"} {
+		if !strings.Contains(view, want) {
+			t.Errorf("Expected Manage Feeds view to contain %q:\n%s", want, view)
+		}
+	}
+
+	model.State = StateFeedView
+	if view := model.viewFeedView(); !strings.Contains(view, "2/4 feeds failing: Broken") {
+		t.Errorf("Expected feed view to list failing feeds:\n%s", view)
+	}
+
+	model.State = StateManageFeeds
+	model.Selected = 3
+	if view := model.viewManageFeeds(); !strings.Contains(view, "Skipped after 3 consecutive failures") {
+		t.Errorf("Expected skipped feed details:\n%s", view)
+	}
+}
+
+func TestRefreshIgnoresSupersededFetch(t *testing.T) {
+	cfg := testConfig(t)
+	feeds := &config.FeedConfig{Feeds: []rss.FeedInfo{{Name: "Feed", URL: "http://127.0.0.1:1/feed"}}}
+	model := NewModel(cfg, feeds, rss.NewClient(5*time.Second))
+
+	model.refreshFeeds()
+	first := model.FetchGeneration
+	model.refreshFeeds()
+	if model.FetchGeneration != first+1 {
+		t.Fatalf("Expected a new generation, got %d after %d", model.FetchGeneration, first)
+	}
+
+	model.Update(FetchMsg{Generation: first, Articles: []rss.Article{{ID: "stale", Title: "Stale"}}})
+	if len(model.Articles) != 0 || !model.Loading {
+		t.Errorf("Expected superseded fetch to be ignored, got %+v", model.Articles)
+	}
+
+	model.Update(FetchMsg{Generation: model.FetchGeneration, Articles: []rss.Article{{ID: "fresh", Title: "Fresh"}}})
+	if len(model.Articles) != 1 || model.Articles[0].ID != "fresh" || model.Loading {
+		t.Errorf("Expected latest fetch to be applied, got %+v", model.Articles)
+	}
+	if model.cancelFetch != nil {
+		t.Error("Expected the finished fetch's context to be released")
+	}
+}
+
+func TestFetchMsgUpdatesSubscriptions(t *testing.T) {
+	cfg := testConfig(t)
+	feeds := &config.FeedConfig{Feeds: []rss.FeedInfo{
+		{Name: "Moved", URL: "https://old.example.com/feed"},
+		{Name: "Gone", URL: "https://gone.example.com/feed"},
+		{Name: "Fine", URL: "https://fine.example.com/feed"},
+	}}
+	model := NewModel(cfg, feeds, rss.NewClient(5*time.Second))
+
+	model.Update(FetchMsg{Results: []rss.FetchResult{
+		{Feed: feeds.Feeds[0], Status: rss.FetchOK, MovedTo: "https://new.example.com/feed"},
+		{Feed: feeds.Feeds[1], Status: rss.FetchGone, Err: &rss.HTTPError{StatusCode: 410}},
+		{Feed: feeds.Feeds[2], Status: rss.FetchOK},
+	}})
+
+	expected := []rss.FeedInfo{
+		{Name: "Moved", URL: "https://new.example.com/feed"},
+		{Name: "Gone", URL: "https://gone.example.com/feed", Dead: true},
+		{Name: "Fine", URL: "https://fine.example.com/feed"},
+	}
+	saved, err := config.LoadFeeds(cfg.FeedsFile)
+	if err != nil {
+		t.Fatalf("Failed to load saved feeds: %v", err)
+	}
+	for i, want := range expected {
+		if !reflect.DeepEqual(model.Feeds.Feeds[i], want) || !reflect.DeepEqual(saved.Feeds[i], want) {
+			t.Errorf("Feed %d: expected %+v, got %+v in memory and %+v on disk", i, want, model.Feeds.Feeds[i], saved.Feeds[i])
+		}
+	}
+
+	if _, ok := model.FeedHealth["https://new.example.com/feed"]; !ok {
+		t.Error("Expected health to be tracked under the new URL")
+	}
+	if !model.ShowNotification || !strings.Contains(model.NotificationMsg, "Moved moved to https://new.example.com/feed") ||
+		!strings.Contains(model.NotificationMsg, "Gone is gone") {
+		t.Errorf("Expected notification about the changes, got %q", model.NotificationMsg)
+	}
+	if failing := model.failingFeeds(); len(failing) != 0 {
+		t.Errorf("Expected dead feeds not to be reported as failing, got %+v", failing)
+	}
+}
+
+func TestUnlockCredentials(t *testing.T) {
+	cfg := testConfig(t)
+
+	store := config.NewCredentialStore()
+	if err := store.Unlock("passphrase"); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.SetSecret("jira", "alice", "hunter2"); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Save(cfg.CredentialsFile); err != nil {
+		t.Fatal(err)
+	}
+
+	model := NewModel(cfg, &config.FeedConfig{}, rss.NewClient(5*time.Second))
+	if model.State != StateUnlock {
+		t.Fatalf("Expected to start on the unlock screen, got %v", model.State)
+	}
+
+	for _, r := range "wrong" {
+		model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{r}})
+	}
+	if view := model.viewUnlock(); strings.Contains(view, "wrong") || !strings.Contains(view, "*****") {
+		t.Errorf("Expected passphrase to be masked:\n%s", view)
+	}
+	_, cmd := model.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if !model.Unlocking || cmd == nil {
+		t.Fatal("Expected unlocking to start")
+	}
+	model.Update(cmd())
+	if model.State != StateUnlock || !errors.Is(model.Err, config.ErrWrongPassphrase) {
+		t.Errorf("Expected to stay on the unlock screen with an error, got %v: %v", model.State, model.Err)
+	}
+
+	for _, r := range "passphrase" {
+		model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{r}})
+	}
+	_, cmd = model.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	model.Update(cmd())
+	if model.State != StateMenu || model.Err != nil {
+		t.Errorf("Expected to continue to the menu, got %v: %v", model.State, model.Err)
+	}
+	if _, secret, err := model.Credentials.Credential("jira"); err != nil || secret != "hunter2" {
+		t.Errorf("Expected unlocked credential, got %q, %v", secret, err)
+	}
+}
+
+func TestEphemeralFeedsNotSaved(t *testing.T) {
+	cfg := testConfig(t)
+	saved := &config.FeedConfig{Feeds: []rss.FeedInfo{{Name: "Saved", URL: "https://saved.example.com/feed"}}}
+	if err := saved.Save(cfg.FeedsFile); err != nil {
+		t.Fatal(err)
+	}
+
+	feeds := &config.FeedConfig{Feeds: []rss.FeedInfo{{Name: "Command Line Feed", URL: "http://example.com/feed"}}, Ephemeral: true}
+	model := NewModel(cfg, feeds, rss.NewClient(5*time.Second))
+	model.Update(FetchMsg{Results: []rss.FetchResult{
+		{Feed: feeds.Feeds[0], Status: rss.FetchOK, MovedTo: "https://example.com/feed"},
+	}})
+
+	if model.Feeds.Feeds[0].URL != "https://example.com/feed" {
+		t.Errorf("Expected the redirect to be followed in memory, got %+v", model.Feeds.Feeds[0])
+	}
+	loaded, err := config.LoadFeeds(cfg.FeedsFile)
+	if err != nil {
+		t.Fatalf("Failed to load feeds: %v", err)
+	}
+	if !reflect.DeepEqual(loaded.Feeds, saved.Feeds) {
+		t.Errorf("Expected the feeds file to be left alone, got %+v", loaded.Feeds)
+	}
+}
+
+func TestDescribeFetchResult(t *testing.T) {
+	remote := describeFetchResult(rss.FetchResult{Status: rss.FetchOK, HTTPStatus: 200, ItemCount: 3})
+	if !strings.Contains(remote, "HTTP 200, 3 items") {
+		t.Errorf("Expected the HTTP status to be described, got %q", remote)
+	}
+	local := describeFetchResult(rss.FetchResult{Feed: rss.FeedInfo{URL: "file:///tmp/feed.xml"}, Status: rss.FetchOK, ItemCount: 3})
+	if strings.Contains(local, "HTTP") || !strings.Contains(local, "3 items") {
+		t.Errorf("Expected no HTTP status for a local feed, got %q", local)
+	}
+}
+
+func TestCorruptFeedCache(t *testing.T) {
+	cfg := testConfig(t)
+	if err := os.WriteFile(cfg.CacheFile, []byte(`{"version":2,"feeds":{`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	model := NewModel(cfg, &config.FeedConfig{}, rss.NewClient(5*time.Second))
+	if model.RSSClient.Cache() == nil {
+		t.Fatal("Expected an empty cache in place of the corrupt one")
+	}
+	if model.Err == nil || !strings.Contains(model.Err.Error(), "feed cache") {
+		t.Errorf("Expected the load error to be reported, got %v", model.Err)
+	}
+
+	model.saveFeedCache()
+	if _, err := config.LoadFeedCache(cfg.CacheFile); err != nil {
+		t.Errorf("Expected the corrupt cache to be overwritten, got %v", err)
+	}
+}
+
+func TestFailingFeedsLineTruncation(t *testing.T) {
+	feeds := &config.FeedConfig{Feeds: []rss.FeedInfo{{Name: "Café über alles", URL: "https://example.com/feed"}}}
+	model := NewModel(testConfig(t), feeds, rss.NewClient(5*time.Second))
+	failing := []rss.FetchResult{{Feed: feeds.Feeds[0], Status: rss.FetchFailed, Failures: 3, Err: errors.New("timeout")}}
+
+	for width := 10; width <= 60; width++ {
+		model.Width = width
+		line := model.failingFeedsLine(failing)
+		if !utf8.ValidString(line) || runewidth.StringWidth(line) > width {
+			t.Errorf("Width %d: expected a valid line that fits, got %q", width, line)
+		}
+	}
+}
@@ -1,10 +1,13 @@
 package tui
 
 import (
+	"fmt"
+	"os"
 	"strings"
 	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
+	"rsss/pkg/download"
 	"rsss/pkg/rss"
 )
 
@@ -12,11 +15,12 @@ import (
 func (m *Model) Init() tea.Cmd {
 	var cmds []tea.Cmd
 
-	if len(m.Feeds.Feeds) > 0 {
-		cmds = append(cmds, FetchAllFeedsCmd(m.RSSClient, m.Feeds))
+	// Feeds using stored credentials are fetched once the store is unlocked
+	if len(m.Feeds.Feeds) > 0 && m.State != StateUnlock {
+		cmds = append(cmds, m.refreshFeeds())
 	}
 
-	cmds = append(cmds, TickCmd(m.Config.RefreshRate))
+	cmds = append(cmds, TickCmd(m.Config.RefreshRate), WaitForDownloadCmd(m.Downloads))
 
 	return tea.Batch(cmds...)
 }
@@ -32,6 +36,11 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		return m.handleKeyPress(msg)
 
 	case FetchMsg:
+		if msg.Generation != m.FetchGeneration {
+			// Superseded by a newer refresh
+			return m, nil
+		}
+		m.cancelRefresh()
 		m.Loading = false
 		
 		// Check for new articles before updating the article list
@@ -42,12 +51,49 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.Articles = msg.Articles
 		m.Err = msg.Err
 		m.LastRefresh = time.Now()
+		m.FeedHealth = make(map[string]rss.FetchResult, len(msg.Results))
+		for _, result := range m.updateSubscriptions(msg.Results) {
+			m.FeedHealth[result.Feed.URL] = result
+		}
+		m.saveFeedCache()
 		m.Selected = 0
 		m.ViewportTop = 0 // Reset viewport when new articles load
 
+	case DiscoverMsg:
+		m.Discovering = false
+		if m.State != StateAddFeed {
+			// The user left the Add Feed screen while the URL was being checked
+			return m, nil
+		}
+		if msg.Err != nil {
+			// Stay on the Add Feed screen so the URL can be corrected
+			m.Err = msg.Err
+			return m, nil
+		}
+		if len(msg.Feeds) == 1 {
+			m.Input = ""
+			return m, m.addFeed(msg.Name, msg.Feeds[0])
+		}
+		m.Input = ""
+		m.Discovered = msg.Feeds
+		m.PendingFeedName = msg.Name
+		m.State = StateChooseFeed
+		m.Selected = 0
+
+	case UnlockMsg:
+		m.Unlocking = false
+		m.Input = ""
+		if msg.Err != nil {
+			m.Err = msg.Err
+			return m, nil
+		}
+		m.Err = nil
+		m.State = StateMenu
+		return m, m.refreshFeeds()
+
 	case TickMsg:
 		if time.Since(m.LastRefresh) >= m.Config.RefreshRate {
-			return m, FetchAllFeedsCmd(m.RSSClient, m.Feeds)
+			return m, m.refreshFeeds()
 		}
 		return m, TickCmd(m.Config.RefreshRate)
 
@@ -58,6 +104,23 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			m.Err = msg.Err
 		}
 
+	case DownloadMsg:
+		if msg.Err != nil {
+			m.Err = msg.Err
+			return m, nil
+		}
+		if msg.Progress.Status == download.StatusFailed {
+			m.Err = fmt.Errorf("download failed: %w", msg.Progress.Err)
+		}
+		// Updates from the manager arrive one at a time, so keep listening
+		if msg.Progress.Status != download.StatusQueued {
+			return m, WaitForDownloadCmd(m.Downloads)
+		}
+
+	case PlayMediaMsg:
+		// Stay in the article view so playback can be retried or downloaded
+		m.Err = msg.Err
+
 	case OpenURLMsg:
 		m.State = StateFeedView // Return to feed view after opening URL
 		if msg.Err != nil {
@@ -93,6 +156,10 @@ func (m *Model) handleKeyPress(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		return m.updateAddFeed(msg)
 	case StateRemoveFeed:
 		return m.updateRemoveFeed(msg)
+	case StateChooseFeed:
+		return m.updateChooseFeed(msg)
+	case StateUnlock:
+		return m.updateUnlock(msg)
 	}
 	return m, nil
 }
@@ -101,6 +168,7 @@ func (m *Model) handleKeyPress(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 func (m *Model) updateMenu(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	switch msg.String() {
 	case "ctrl+c", "q":
+		m.cancelRefresh()
 		return m, tea.Quit
 	case "up", "k":
 		if m.MenuSelected > 0 {
@@ -157,7 +225,7 @@ func (m *Model) updateFeedView(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		}
 	case "r":
 		m.Loading = true
-		return m, FetchAllFeedsCmd(m.RSSClient, m.Feeds)
+		return m, m.refreshFeeds()
 	}
 	return m, nil
 }
@@ -179,6 +247,7 @@ func (m *Model) updateManageFeeds(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	case "a":
 		m.State = StateAddFeed
 		m.Input = ""
+		m.Err = nil
 		return m, nil
 	case "d":
 		if len(m.Feeds.Feeds) > 0 {
@@ -246,20 +315,23 @@ func (m *Model) updateAddFeed(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		m.State = StateManageFeeds
 		return m, nil
 	case "enter":
-		if m.Input != "" {
+		if m.Input != "" && !m.Discovering {
 			parts := strings.SplitN(m.Input, "|", 2)
-			name := strings.TrimSpace(parts[0])
-			url := name
+			if strings.HasPrefix(m.Input, rss.ExecPrefix) {
+				// Commands may contain pipes
+				parts = []string{m.Input}
+			}
+			name := ""
+			url := strings.TrimSpace(parts[0])
 			if len(parts) == 2 {
+				name = url
 				url = strings.TrimSpace(parts[1])
 			}
 
-			m.Feeds.Feeds = append(m.Feeds.Feeds, rss.FeedInfo{Name: name, URL: url})
-			if err := m.Feeds.Save(m.Config.FeedsFile); err != nil {
-				m.Err = err
-			}
-			m.State = StateManageFeeds
-			return m, FetchAllFeedsCmd(m.RSSClient, m.Feeds)
+			// Check the URL first so that website URLs can be resolved to their feeds
+			m.Discovering = true
+			m.Err = nil
+			return m, DiscoverFeedsCmd(m.RSSClient, name, url)
 		}
 	case "backspace":
 		if len(m.Input) > 0 {
@@ -271,6 +343,50 @@ func (m *Model) updateAddFeed(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	return m, nil
 }
 
+// updateChooseFeed handles choosing one of the feeds discovered on a web page
+func (m *Model) updateChooseFeed(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "ctrl+c", "esc":
+		m.State = StateAddFeed
+		m.Discovered = nil
+		return m, nil
+	case "up", "k":
+		if m.Selected > 0 {
+			m.Selected--
+		}
+	case "down", "j":
+		if m.Selected < len(m.Discovered)-1 {
+			m.Selected++
+		}
+	case "enter":
+		if m.Selected < len(m.Discovered) {
+			return m, m.addFeed(m.PendingFeedName, m.Discovered[m.Selected])
+		}
+	}
+	return m, nil
+}
+
+// addFeed subscribes to a discovered feed, naming it after the feed's title
+// when no name was entered
+func (m *Model) addFeed(name string, feed rss.DiscoveredFeed) tea.Cmd {
+	if name == "" {
+		name = feed.Title
+	}
+	if name == "" {
+		name = feed.URL
+	}
+
+	m.Feeds.Feeds = append(m.Feeds.Feeds, rss.FeedInfo{Name: name, URL: feed.URL})
+	if err := m.Feeds.Save(m.Config.FeedsFile); err != nil {
+		m.Err = err
+	}
+	m.Discovered = nil
+	m.PendingFeedName = ""
+	m.State = StateManageFeeds
+	m.Selected = len(m.Feeds.Feeds) - 1
+	return m.refreshFeeds()
+}
+
 // updateRemoveFeed handles feed removal
 func (m *Model) updateRemoveFeed(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	switch msg.String() {
@@ -293,6 +409,8 @@ func (m *Model) updateRemoveFeed(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		}
 	case "enter":
 		if m.Selected < len(m.Feeds.Feeds) {
+			m.RSSClient.Cache().Delete(m.Feeds.Feeds[m.Selected].URL)
+
 			// Remove feed using slices operations for better performance
 			copy(m.Feeds.Feeds[m.Selected:], m.Feeds.Feeds[m.Selected+1:])
 			m.Feeds.Feeds = m.Feeds.Feeds[:len(m.Feeds.Feeds)-1]
@@ -307,7 +425,7 @@ func (m *Model) updateRemoveFeed(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 			} else if len(m.Feeds.Feeds) == 0 {
 				m.Selected = 0
 			}
-			return m, FetchAllFeedsCmd(m.RSSClient, m.Feeds)
+			return m, m.refreshFeeds()
 		}
 	}
 	return m, nil
@@ -324,14 +442,66 @@ func (m *Model) updateArticleView(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		if len(m.Articles) > 0 && m.Selected < len(m.Articles) {
 			return m, OpenURLCmd(m.Articles[m.Selected].Link)
 		}
+	case "p":
+		// 'p' for "play" - hand the enclosure to the configured media player,
+		// preferring a completed download over streaming the URL
+		if article := m.GetSelectedArticle(); article != nil && len(article.Enclosures) > 0 {
+			enclosure := article.Enclosures[0]
+			target := enclosure.URL
+			if path := m.Downloads.Path(enclosure.URL, article.FeedName); fileExists(path) {
+				target = path
+			}
+			return m, PlayMediaCmd(m.Config.MediaPlayers, enclosure.Type, target)
+		}
+	case "d":
+		// 'd' for "download" - queue the first enclosure for download
+		if article := m.GetSelectedArticle(); article != nil && len(article.Enclosures) > 0 {
+			return m, DownloadCmd(m.Downloads, article.Enclosures[0].URL, article.FeedName)
+		}
 	}
 	return m, nil
 }
 
+// fileExists reports whether a regular file exists at path
+func fileExists(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && !info.IsDir()
+}
+
 // GetSelectedArticle returns the currently selected article
 func (m *Model) GetSelectedArticle() *rss.Article {
 	if len(m.Articles) > 0 && m.Selected < len(m.Articles) {
 		return &m.Articles[m.Selected]
 	}
 	return nil
+}
+
+// updateUnlock handles passphrase entry for the credential store
+func (m *Model) updateUnlock(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if m.Unlocking {
+		return m, nil
+	}
+	switch msg.String() {
+	case "ctrl+c":
+		return m, tea.Quit
+	case "esc":
+		// Carry on without the encrypted credentials
+		m.Input = ""
+		m.Err = nil
+		m.State = StateMenu
+		return m, m.refreshFeeds()
+	case "enter":
+		if m.Input != "" {
+			m.Unlocking = true
+			m.Err = nil
+			return m, UnlockCmd(m.Credentials, m.Input)
+		}
+	case "backspace":
+		if len(m.Input) > 0 {
+			m.Input = m.Input[:len(m.Input)-1]
+		}
+	default:
+		m.Input += msg.String()
+	}
+	return m, nil
 }
\ No newline at end of file
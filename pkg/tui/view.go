@@ -3,6 +3,11 @@ package tui
 import (
 	"fmt"
 	"strings"
+	"time"
+
+	"github.com/mattn/go-runewidth"
+	"rsss/pkg/download"
+	"rsss/pkg/rss"
 )
 
 // View renders the current state of the TUI
@@ -24,6 +29,10 @@ func (m *Model) View() string {
 		content = m.viewAddFeed()
 	case StateRemoveFeed:
 		content = m.viewRemoveFeed()
+	case StateChooseFeed:
+		content = m.viewChooseFeed()
+	case StateUnlock:
+		content = m.viewUnlock()
 	default:
 		content = "Unknown state"
 	}
@@ -69,6 +78,10 @@ func (m *Model) viewFeedView() string {
 	// Compact header - just the essential info on one line
 	headerInfo := fmt.Sprintf("📰 Latest Articles | Updated: %s", m.LastRefresh.Format("15:04:05"))
 	
+	if active := m.Downloads.Active(); active > 0 {
+		headerInfo += fmt.Sprintf(" | ⬇️ %d downloading", active)
+	}
+	
 	if m.Err != nil {
 		headerInfo += fmt.Sprintf(" | Error: %v", m.Err)
 	}
@@ -76,6 +89,11 @@ func (m *Model) viewFeedView() string {
 	b.WriteString(m.Styles.Title.Render(headerInfo))
 	b.WriteString("\n")
 
+	if failing := m.failingFeeds(); len(failing) > 0 {
+		b.WriteString(m.Styles.Error.Render(m.failingFeedsLine(failing)))
+		b.WriteString("\n")
+	}
+
 	// Handle special states
 	if m.Loading {
 		b.WriteString(m.Styles.Normal.Render("Loading feeds..."))
@@ -125,6 +143,9 @@ func (m *Model) viewFeedView() string {
 
 		// Format time and feed name with responsive width
 		timeStr := article.PubDate.Format("15:04")
+		if article.Undated {
+			timeStr = "--:--" // Only the first-seen time is known
+		}
 		feedName := article.FeedName
 		
 		// Adjust feed name width based on terminal size
@@ -172,7 +193,24 @@ func (m *Model) viewManageFeeds() string {
 			if i == m.Selected {
 				style = m.Styles.Selected
 			}
-			b.WriteString(style.Render(fmt.Sprintf("%d. %s (%s)", i+1, feed.Name, feed.URL)))
+			result, fetched := m.FeedHealth[feed.URL]
+			b.WriteString(style.Render(fmt.Sprintf("%s %d. %s (%s)", healthIndicator(feed, result, fetched), i+1, feed.Name, feed.URL)))
+			b.WriteString("\n")
+		}
+
+		if m.Selected < len(m.Feeds.Feeds) {
+			b.WriteString("\n")
+			if feed := m.Feeds.Feeds[m.Selected]; feed.Dead {
+				b.WriteString(m.Styles.Error.Render("Gone: the feed answered 410 Gone and is no longer fetched"))
+			} else if result, ok := m.FeedHealth[feed.URL]; ok {
+				style := m.Styles.Success
+				if result.Err != nil {
+					style = m.Styles.Error
+				}
+				b.WriteString(style.Render(describeFetchResult(result)))
+			} else {
+				b.WriteString(m.Styles.Normal.Render("Not fetched yet"))
+			}
 			b.WriteString("\n")
 		}
 	}
@@ -239,17 +277,54 @@ func (m *Model) viewAddFeed() string {
 	b.WriteString("\n")
 	b.WriteString(m.Styles.Selected.Render(m.Input + "█"))
 	b.WriteString("\n\n")
+	if m.Discovering {
+		b.WriteString(m.Styles.Accent.Render("Checking URL for feeds..."))
+		b.WriteString("\n\n")
+	} else if m.Err != nil {
+		b.WriteString(m.Styles.Error.Render(fmt.Sprintf("Error: %v", m.Err)))
+		b.WriteString("\n\n")
+	}
 	b.WriteString(m.Styles.Normal.Render("Examples:"))
 	b.WriteString("\n")
 	b.WriteString(m.Styles.Normal.Render("  BBC News|https://feeds.bbci.co.uk/news/rss.xml"))
 	b.WriteString("\n")
 	b.WriteString(m.Styles.Normal.Render("  https://feeds.bbci.co.uk/news/rss.xml"))
+	b.WriteString("\n")
+	b.WriteString(m.Styles.Normal.Render("  https://example.com (feeds on the page are detected)"))
 	b.WriteString("\n\n")
 	b.WriteString(m.Styles.Normal.Render("Press Enter to save, Esc to cancel"))
 
 	return b.String()
 }
 
+// viewChooseFeed renders the list of feeds discovered on a web page
+func (m *Model) viewChooseFeed() string {
+	var b strings.Builder
+
+	b.WriteString(m.Styles.Title.Render("🔍 Choose Feed"))
+	b.WriteString("\n\n")
+	b.WriteString(m.Styles.Normal.Render("The page offers several feeds:"))
+	b.WriteString("\n\n")
+
+	for i, feed := range m.Discovered {
+		style := m.Styles.Normal
+		if i == m.Selected {
+			style = m.Styles.Selected
+		}
+		title := feed.Title
+		if title == "" {
+			title = feed.Type
+		}
+		b.WriteString(style.Render(fmt.Sprintf("%d. %s (%s)", i+1, title, feed.URL)))
+		b.WriteString("\n")
+	}
+
+	b.WriteString("\n")
+	b.WriteString(m.Styles.Normal.Render("Use ↑/↓ to select, Enter to add, Esc to go back"))
+
+	return b.String()
+}
+
 // viewRemoveFeed renders the remove feed view
 func (m *Model) viewRemoveFeed() string {
 	var b strings.Builder
@@ -308,6 +383,9 @@ func (m *Model) viewArticleView() string {
 
 	// Article metadata - compact for mobile, expanded for wider screens
 	timeStr := article.PubDate.Format("15:04 on 2006-01-02")
+	if article.Undated {
+		timeStr = "first seen " + timeStr
+	}
 	if terminalWidth < 60 {
 		// Compact layout for narrow screens
 		b.WriteString(m.Styles.Accent.Render(fmt.Sprintf("🕒 %s", timeStr)))
@@ -318,6 +396,15 @@ func (m *Model) viewArticleView() string {
 		b.WriteString(m.Styles.Accent.Render(fmt.Sprintf("🕒 %s | 📰 %s", timeStr, article.FeedName)))
 	}
 	b.WriteString("\n")
+
+	if article.Author != "" {
+		b.WriteString(m.Styles.Normal.Render(fmt.Sprintf("✍️ %s", article.Author)))
+		b.WriteString("\n")
+	}
+	if len(article.Categories) > 0 {
+		b.WriteString(m.Styles.Normal.Render(fmt.Sprintf("🏷️ %s", strings.Join(article.Categories, ", "))))
+		b.WriteString("\n")
+	}
 	
 	// URL - wrap if too long
 	url := article.Link
@@ -325,12 +412,46 @@ func (m *Model) viewArticleView() string {
 		url = url[:terminalWidth-7] + "..."
 	}
 	b.WriteString(m.Styles.Normal.Render(fmt.Sprintf("🔗 %s", url)))
-	b.WriteString("\n\n")
+	b.WriteString("\n")
+
+	if article.CommentsURL != "" || article.CommentCount > 0 {
+		comments := fmt.Sprintf("💬 %d comments", article.CommentCount)
+		if article.CommentsURL != "" {
+			comments += " | " + article.CommentsURL
+		}
+		if len(comments) > terminalWidth-4 {
+			comments = comments[:terminalWidth-7] + "..."
+		}
+		b.WriteString(m.Styles.Normal.Render(comments))
+		b.WriteString("\n")
+	}
+
+	// Podcast episode details and enclosure download state
+	if len(article.Enclosures) > 0 {
+		b.WriteString(m.Styles.Accent.Render(m.formatEnclosure(article)))
+		b.WriteString("\n")
+		if status := m.downloadStatus(article.Enclosures[0].URL); status != "" {
+			b.WriteString(m.Styles.Normal.Render(status))
+			b.WriteString("\n")
+		}
+	}
+	if article.Image != "" {
+		image := article.Image
+		if len(image) > terminalWidth-4 {
+			image = image[:terminalWidth-7] + "..."
+		}
+		b.WriteString(m.Styles.Normal.Render(fmt.Sprintf("🖼️ %s", image)))
+		b.WriteString("\n")
+	}
+	b.WriteString("\n")
 
-	// Article content
-	if article.Description != "" {
+	// Article content - prefer the full body over the teaser description
+	content := article.Content
+	if content == "" {
+		content = article.Description
+	}
+	if content != "" {
 		// Clean up HTML tags and decode entities for better readability
-		content := article.Description
 		
 		// Basic HTML tag removal (simple approach)
 		content = strings.ReplaceAll(content, "<br>", "\n")
@@ -381,7 +502,15 @@ func (m *Model) viewArticleView() string {
 	}
 
 	b.WriteString("\n\n")
-	b.WriteString(m.Styles.Normal.Render("Press 'o' to open in browser, Esc to return to feed list"))
+	if m.Err != nil {
+		b.WriteString(m.Styles.Error.Render(fmt.Sprintf("Error: %v", m.Err)))
+		b.WriteString("\n")
+	}
+	if len(article.Enclosures) > 0 {
+		b.WriteString(m.Styles.Normal.Render("Press 'o' to open in browser, 'p' to play, 'd' to download enclosure, Esc to return to feed list"))
+	} else {
+		b.WriteString(m.Styles.Normal.Render("Press 'o' to open in browser, Esc to return to feed list"))
+	}
 
 	return b.String()
 }
@@ -404,5 +533,158 @@ func (m *Model) addNotificationOverlay(content string) string {
 	// Add the main content
 	b.WriteString(content)
 	
+	return b.String()
+}
+
+// formatEnclosure describes an article's first enclosure and episode details
+func (m *Model) formatEnclosure(article rss.Article) string {
+	enclosure := article.Enclosures[0]
+	parts := []string{"🎧 " + enclosure.Type}
+	if enclosure.Type == "" {
+		parts[0] = "🎧 enclosure"
+	}
+	if enclosure.Length > 0 {
+		parts = append(parts, formatBytes(enclosure.Length))
+	}
+	if article.Duration > 0 {
+		parts = append(parts, formatDuration(article.Duration))
+	}
+	if article.Episode > 0 {
+		parts = append(parts, fmt.Sprintf("Episode %d", article.Episode))
+	}
+	if len(article.Enclosures) > 1 {
+		parts = append(parts, fmt.Sprintf("+%d more", len(article.Enclosures)-1))
+	}
+	return strings.Join(parts, " | ")
+}
+
+// downloadStatus describes the download state of an enclosure URL
+func (m *Model) downloadStatus(url string) string {
+	progress, ok := m.Downloads.Get(url)
+	if !ok {
+		return ""
+	}
+
+	switch progress.Status {
+	case download.StatusQueued:
+		return "⏳ Queued for download"
+	case download.StatusDownloading:
+		if percent := progress.Percent(); percent >= 0 {
+			return fmt.Sprintf("⬇️ Downloading %d%% (%s of %s)", percent, formatBytes(progress.Downloaded), formatBytes(progress.Total))
+		}
+		return fmt.Sprintf("⬇️ Downloading %s", formatBytes(progress.Downloaded))
+	case download.StatusCompleted:
+		return fmt.Sprintf("✅ Saved to %s", progress.Path)
+	case download.StatusFailed:
+		return fmt.Sprintf("❌ Download failed: %v", progress.Err)
+	}
+	return ""
+}
+
+// formatBytes formats a byte count using binary units
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for v := n / unit; v >= unit; v /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+// formatDuration formats an episode duration as H:MM:SS or M:SS
+func formatDuration(d time.Duration) string {
+	total := int(d.Seconds())
+	hours, minutes, seconds := total/3600, (total%3600)/60, total%60
+	if hours > 0 {
+		return fmt.Sprintf("%d:%02d:%02d", hours, minutes, seconds)
+	}
+	return fmt.Sprintf("%d:%02d", minutes, seconds)
+}
+
+// failingFeedsLine summarizes the feeds whose last fetch failed on one line
+func (m *Model) failingFeedsLine(failing []rss.FetchResult) string {
+	parts := make([]string, len(failing))
+	for i, result := range failing {
+		if result.Failures > 1 {
+			parts[i] = fmt.Sprintf("%s (%d× %v)", result.Feed.Name, result.Failures, result.Err)
+		} else {
+			parts[i] = fmt.Sprintf("%s (%v)", result.Feed.Name, result.Err)
+		}
+	}
+	line := fmt.Sprintf("⚠️ %d/%d feeds failing: %s", len(failing), len(m.Feeds.Feeds), strings.Join(parts, ", "))
+
+	width := m.Width
+	if width == 0 {
+		width = 80
+	}
+	// Feed names and markers are multi-byte, so cut by display width
+	return runewidth.Truncate(line, width, "...")
+}
+
+// healthIndicator returns a marker for the outcome of a feed's last fetch
+func healthIndicator(feed rss.FeedInfo, result rss.FetchResult, fetched bool) string {
+	switch {
+	case feed.Dead:
+		return "☠"
+	case !fetched:
+		return "·"
+	case result.Status == rss.FetchFailed:
+		return "✗"
+	case result.Status == rss.FetchSkipped:
+		return "⏸"
+	default:
+		return "✓"
+	}
+}
+
+// describeFetchResult describes the outcome of a feed's last fetch
+func describeFetchResult(result rss.FetchResult) string {
+	duration := result.Duration.Round(time.Millisecond)
+	if result.Status == rss.FetchSkipped {
+		return fmt.Sprintf("Skipped after %d consecutive failures until %s: %v",
+			result.Failures, result.NextAttempt.Format("15:04"), result.Err)
+	}
+	if result.Err != nil {
+		description := fmt.Sprintf("Last fetch failed after %s: %v", duration, result.Err)
+		if result.HTTPStatus != 0 {
+			description = fmt.Sprintf("Last fetch failed after %s (HTTP %d): %v", duration, result.HTTPStatus, result.Err)
+		}
+		if result.Failures > 1 {
+			description += fmt.Sprintf(" (%d consecutive failures, next attempt at %s)",
+				result.Failures, result.NextAttempt.Format("15:04"))
+		}
+		return description
+	}
+	if result.HTTPStatus == 0 {
+		// Local sources have no HTTP status
+		return fmt.Sprintf("Last fetch %s: %d items in %s", result.Status, result.ItemCount, duration)
+	}
+	return fmt.Sprintf("Last fetch %s: HTTP %d, %d items in %s", result.Status, result.HTTPStatus, result.ItemCount, duration)
+}
+
+// viewUnlock renders the passphrase prompt for the credential store
+func (m *Model) viewUnlock() string {
+	var b strings.Builder
+
+	b.WriteString(m.Styles.Title.Render("🔒 Unlock Credentials"))
+	b.WriteString("\n\n")
+
+	b.WriteString(m.Styles.Normal.Render("Enter the passphrase for stored feed credentials:"))
+	b.WriteString("\n")
+	b.WriteString(m.Styles.Selected.Render(strings.Repeat("*", len([]rune(m.Input))) + "█"))
+	b.WriteString("\n\n")
+	if m.Unlocking {
+		b.WriteString(m.Styles.Accent.Render("Unlocking..."))
+		b.WriteString("\n\n")
+	} else if m.Err != nil {
+		b.WriteString(m.Styles.Error.Render(fmt.Sprintf("Error: %v", m.Err)))
+		b.WriteString("\n\n")
+	}
+	b.WriteString(m.Styles.Normal.Render("Press Enter to unlock, Esc to skip (feeds using stored credentials will fail)"))
+
 	return b.String()
 }
\ No newline at end of file